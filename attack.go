@@ -2,6 +2,7 @@ package loaderbot
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,13 +25,22 @@ type Attack interface {
 func attack(a Attack, r *Runner) {
 	for nextMsg := range r.next {
 		token := nextMsg
+		atomic.AddInt64(&r.busyAttackers, 1)
 		requestCtx, requestCtxCancel := context.WithTimeout(context.Background(), time.Duration(r.Cfg.AttackerTimeout)*time.Second)
+		traceCollector := &httpTraceCollector{}
+		requestCtx = withHTTPTraceCollector(requestCtx, traceCollector)
+		var span *SpanContext
+		if r.otel != nil {
+			requestCtx, span = r.otel.sample(requestCtx)
+		}
 
 		tStart := time.Now()
 
 		done := make(chan DoResult, 1)
 		var doResult DoResult
 		go func() {
+			atomic.AddInt64(&r.inFlight, 1)
+			defer atomic.AddInt64(&r.inFlight, -1)
 			select {
 			case <-r.TimeoutCtx.Done():
 				requestCtxCancel()
@@ -43,6 +53,7 @@ func attack(a Attack, r *Runner) {
 		select {
 		case <-r.TimeoutCtx.Done():
 			requestCtxCancel()
+			atomic.AddInt64(&r.busyAttackers, -1)
 			return
 		case <-requestCtx.Done():
 			doResult = DoResult{
@@ -60,11 +71,16 @@ func attack(a Attack, r *Runner) {
 			End:         tEnd,
 			Elapsed:     tEnd.Sub(tStart),
 			DoResult:    doResult,
+			HTTPTimings: traceCollector.result(),
+		}
+		if span != nil {
+			r.otel.addSpan(span, atkResult)
 		}
 		requestCtxCancel()
 		if err := a.Teardown(); err != nil {
 			r.L.Infof("teardown failed: %s", err)
 		}
 		r.results <- atkResult
+		atomic.AddInt64(&r.busyAttackers, -1)
 	}
 }
@@ -2,6 +2,8 @@ package loaderbot
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -62,3 +64,56 @@ func TestCommonAttackTimeout(t *testing.T) {
 		t.Fatalf("got %v want %v", got, want)
 	}
 }
+
+func TestCommonAttackHTTPTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	cfg := DefaultRunnerCfg()
+	cfg.TargetUrl = srv.URL
+	r := NewRunner(cfg, &HTTPAttackerExample{}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Cfg.TestTimeSec)*time.Second)
+	r.TimeoutCtx = ctx
+	r.CancelFunc = cancel
+
+	go attack(r.attackers[0], r)
+	for i := 0; i < 2; i++ {
+		r.next <- attackToken{
+			Step: 1,
+			Tick: 1,
+		}
+		res := <-r.results
+		if res.HTTPTimings == nil {
+			t.Fatal("http timings are not recorded")
+		}
+		if got, want := res.HTTPTimings.Requests, 1; got != want {
+			t.Fatalf("got %v want %v", got, want)
+		}
+		if got, want := res.HTTPTimings.TTFB, 20*time.Millisecond; got < want {
+			t.Fatalf("got %v want >= %v", got, want)
+		}
+	}
+}
+
+type idleClosingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *idleClosingTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestCommonTracingTransportCloseIdleConnections(t *testing.T) {
+	transport := &idleClosingTransport{RoundTripper: http.DefaultTransport}
+	c := &http.Client{Transport: &TracingTransport{transport}}
+	c.CloseIdleConnections()
+	if got, want := transport.closed, 1; got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	// transports without idle connections are skipped
+	c = &http.Client{Transport: &TracingTransport{&DumpTransport{http.DefaultTransport}}}
+	c.CloseIdleConnections()
+}
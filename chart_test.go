@@ -1,10 +1,15 @@
 package loaderbot
 
 import (
+	"bytes"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-echarts/go-echarts/charts"
 	"github.com/stretchr/testify/require"
+	"github.com/wcharczuk/go-chart"
 )
 
 func TestCommonReportScaling(t *testing.T) {
@@ -27,3 +32,96 @@ func TestCommonRenderErr(t *testing.T) {
 	_, err := PercsChart("example_csv_data/empty.csv", "Response times")
 	require.Error(t, err)
 }
+
+func TestCommonRenderCreateErr(t *testing.T) {
+	// page is not rendered if file can't be created
+	RenderEPage(charts.NewPage(), "no_such_dir/page.html")
+	RenderEChart(charts.NewLine(), "no_such_dir/line.html")
+	require.NoDirExists(t, "no_such_dir")
+}
+
+func TestCommonDashboard(t *testing.T) {
+	d := NewDashboard("dashboard")
+	// run and label metrics are aggregated by runner
+	run := NewMetrics()
+	labels := map[string]*Metrics{"get": NewMetrics(), "put": NewMetrics()}
+	for tick := 0; tick < 4; tick++ {
+		token := attackToken{TargetRPS: 10, Step: tick/2 + 1, Tick: tick}
+		m := NewMetrics()
+		samples := make([]AttackResult, 0)
+		for i := 0; i < 10; i++ {
+			res := AttackResult{
+				AttackToken: token,
+				Begin:       time.Now(),
+				End:         time.Now(),
+				Elapsed:     time.Duration(i*10) * time.Millisecond,
+				DoResult:    DoResult{RequestLabel: "get", StatusCode: 200},
+			}
+			if i%2 == 0 {
+				res.DoResult.RequestLabel = "put"
+			}
+			if i == 0 {
+				res.DoResult = DoResult{RequestLabel: "put", Error: "context deadline exceeded"}
+			}
+			if i == 1 {
+				res.DoResult.StatusCode = 500
+			}
+			m.add(res)
+			run.add(res)
+			labels[res.DoResult.RequestLabel].add(res)
+			samples = append(samples, res)
+		}
+		m.TargetRate = float64(token.TargetRPS)
+		m.update()
+		d.addTick(token, 5, m, samples)
+	}
+	run.update()
+	for _, lm := range labels {
+		lm.update()
+	}
+	d.Run = run
+	d.Labels = labels
+	d.addSummary("Knee", "none")
+	var buf bytes.Buffer
+	require.NoError(t, d.Render(&buf))
+	html := buf.String()
+	require.Equal(t, 8, strings.Count(html, "<svg"))
+	require.Contains(t, html, "<tr><th>Knee</th><td>none</td></tr>")
+	require.Contains(t, html, "200: 32, 500: 4")
+	require.Contains(t, html, "<td>put</td><td>20</td>")
+	require.Equal(t, map[string]int{"timeout": 1, "status": 1}, d.Ticks[0].ErrorCategories)
+	require.Equal(t, uint64(4), d.Histogram[0])
+}
+
+func TestCommonLatencyHeatmap(t *testing.T) {
+	h := NewLatencyHeatmap(nil)
+	start := time.Unix(1600000000, 0)
+	// cache hits and misses, no requests completed in the second second
+	for _, sec := range []int{0, 2} {
+		for i := 0; i < 10; i++ {
+			elapsed := 3 * time.Millisecond
+			if i%2 == 0 {
+				elapsed = 300 * time.Millisecond
+			}
+			end := start.Add(time.Duration(sec) * time.Second)
+			h.Add(AttackResult{Begin: end.Add(-elapsed), End: end, Elapsed: elapsed})
+		}
+	}
+	rows := h.Rows()
+	require.Len(t, rows, 3)
+	require.Equal(t, uint64(5), rows[0][2])
+	require.Equal(t, uint64(5), rows[0][8])
+	require.Equal(t, make([]uint64, len(latencyHistogramBuckets)+1), rows[1])
+	require.Equal(t, "<=5ms", h.BucketLabels()[2])
+
+	_, err := HeatmapChart(h, "heatmap")
+	require.NoError(t, err)
+	c, err := HeatmapPNGChart(h, "heatmap")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, c.Render(chart.PNG, &buf))
+	require.NotZero(t, buf.Len())
+
+	_, err = HeatmapPNGChart(NewLatencyHeatmap(nil), "empty")
+	require.Error(t, err)
+}
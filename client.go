@@ -4,16 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"fmt"
 	"io"
 	"log"
+	"os"
+	"path"
+	"runtime"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
 	"google.golang.org/grpc"
 )
 
 type NodeClient struct {
+	addr string
 	conn *grpc.ClientConn
 	LoaderClient
 	stream Loader_RunClient
@@ -25,6 +32,7 @@ func NewNodeClient(addr string) *NodeClient {
 		log.Fatalf("failed to connect node %s: %v", addr, err)
 	}
 	return &NodeClient{
+		addr:         addr,
 		conn:         conn,
 		LoaderClient: NewLoaderClient(conn),
 		stream:       nil,
@@ -35,7 +43,7 @@ func (m *NodeClient) StartRunner(cluster *ClusterClient) {
 	var err error
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cluster.testCfg.TestTimeSec)*time.Second)
 	defer cancel()
-	nodeCfg := cluster.configToNodes()
+	nodeCfg := cluster.configToNodes(m.addr)
 	m.stream, err = m.Run(ctx, &RunConfigRequest{
 		Config:       MarshalConfigGob(nodeCfg),
 		AttackerName: "http",
@@ -73,10 +81,17 @@ func (m *NodeClient) receive(_ context.Context, cluster *ClusterClient) {
 		if err := dec.Decode(&results); err != nil {
 			cluster.L.Fatal(err)
 		}
-		cluster.Results <- results
+		cluster.Results <- NodeResults{Node: m.addr, Results: results}
 	}
 }
 
+// NodeResults results batch received from cluster node
+type NodeResults struct {
+	// Node address
+	Node    string
+	Results []AttackResult
+}
+
 type ClusterClient struct {
 	TimeoutCtx context.Context
 	CancelFunc context.CancelFunc
@@ -85,13 +100,34 @@ type ClusterClient struct {
 	testCfg            *RunnerConfig
 	activeClients      int32
 	clients            []*NodeClient
-	Results            chan []AttackResult
+	Results            chan NodeResults
 	clusterTickMetrics map[int]*ClusterTickMetrics
 	Report             *Report
-	L                  *Logger
+	// SLO compliance of a run across the cluster and its steps, nil if no SLO is set
+	SLO *SLOReport
+	// RunMetrics metrics aggregated over all nodes and reported ticks
+	RunMetrics *Metrics
+	// LabelMetrics metrics aggregated over all nodes and reported ticks by request label
+	LabelMetrics map[string]*Metrics
+	// Knee saturation analysis of a stepped run, available after run if csv report is enabled
+	Knee *Knee
+	// labelSteps per step metrics over all nodes by request label
+	labelSteps     labelSteps
+	uniqErrors     map[string]int
+	minTickSuccess float64
+	ticksReported  int
+	failureReason  string
+	startTime      time.Time
+	endTime        time.Time
+	Sinks          []MetricSink
+	es             *ElasticsearchSink
+	live           *liveServer
+	L              *Logger
 }
 
 func NewClusterClient(cfg *RunnerConfig) *ClusterClient {
+	cfg.Validate()
+	cfg.DefaultCfgValues()
 	clients := make([]*NodeClient, 0)
 	var failed bool
 	for _, addr := range cfg.ClusterOptions.Nodes {
@@ -109,18 +145,34 @@ func NewClusterClient(cfg *RunnerConfig) *ClusterClient {
 	c := &ClusterClient{
 		testCfg:            cfg,
 		clients:            clients,
-		Results:            make(chan []AttackResult),
+		Results:            make(chan NodeResults),
 		clusterTickMetrics: make(map[int]*ClusterTickMetrics),
 		failed:             failed,
+		SLO:                NewSLOReport(cfg.SLO),
+		RunMetrics:         NewMetrics(),
+		LabelMetrics:       make(map[string]*Metrics),
+		labelSteps:         make(labelSteps),
+		uniqErrors:         make(map[string]int),
 		L:                  NewLogger(cfg).With("cluster", cfg.Name),
 	}
 	if cfg.ReportOptions.CSV {
 		c.Report = NewReport(cfg)
 	}
+	if cfg.LiveDashboard != nil && cfg.LiveDashboard.Enable {
+		c.live = newLiveServer(cfg.Name, cfg.LiveDashboard.Port, c.L)
+	}
+	c.Sinks = newMetricSinks(cfg, c.L)
+	if cfg.Elasticsearch != nil {
+		runID := uuid.New().String()
+		if c.Report != nil {
+			runID = c.Report.runId
+		}
+		c.es = NewElasticsearchSink(cfg.Elasticsearch, runID, cfg.Name, c.L)
+	}
 	return c
 }
 
-func (m *ClusterClient) configToNodes() *RunnerConfig {
+func (m *ClusterClient) configToNodes(node string) *RunnerConfig {
 	var nodeTestCfg RunnerConfig
 	if err := copier.Copy(&nodeTestCfg, &m.testCfg); err != nil {
 		m.L.Fatal(err)
@@ -128,6 +180,18 @@ func (m *ClusterClient) configToNodes() *RunnerConfig {
 	// no need to write logs on nodes in cluster mode
 	nodeTestCfg.ReportOptions.CSV = false
 	nodeTestCfg.ReportOptions.PNG = false
+	// junit report and manifest of the whole run are written by cluster client
+	nodeTestCfg.ReportOptions.JUnit = false
+	// sinks receive cluster aggregate only
+	nodeTestCfg.InfluxDB = nil
+	nodeTestCfg.StatsD = nil
+	nodeTestCfg.Elasticsearch = nil
+	// run summary is posted once by cluster client
+	nodeTestCfg.Slack = nil
+	// live dashboard is served by cluster client, nodes may run in one process
+	nodeTestCfg.LiveDashboard = nil
+	// nodes export otel metrics and spans themselves, node is a resource attribute
+	nodeTestCfg.ClusterOptions.Node = node
 	// split start/step rps by nodes equally
 	nodeTestCfg.Attackers = nodeTestCfg.Attackers / len(nodeTestCfg.ClusterOptions.Nodes)
 	nodeTestCfg.StartRPS = nodeTestCfg.StartRPS / len(nodeTestCfg.ClusterOptions.Nodes)
@@ -144,6 +208,11 @@ func (m *ClusterClient) CheckBusy() bool {
 }
 
 func (m *ClusterClient) Run() {
+	m.startTime = time.Now()
+	if m.live != nil {
+		m.live.start()
+		defer m.live.close()
+	}
 	for _, c := range m.clients {
 		atomic.AddInt32(&m.activeClients, 1)
 		go c.StartRunner(m)
@@ -152,16 +221,54 @@ func (m *ClusterClient) Run() {
 	for _, c := range m.clients {
 		c.Close()
 	}
+	closeSinks(m.Sinks, m.L)
+	if m.es != nil {
+		if err := m.es.Close(); err != nil {
+			m.L.Warn(err)
+		}
+	}
+	m.endTime = time.Now()
+	if m.SLO != nil {
+		for _, step := range m.SLO.sortedSteps() {
+			m.L.Infof("slo step %d: %s", step, m.SLO.Steps[step])
+		}
+		m.L.Infof("slo run: %s", m.SLO.Run)
+	}
+	var maxRPS float64
 	if m.testCfg.ReportOptions.CSV {
+		m.Report.writeSLOSummary(m.SLO)
 		m.Report.flushLogs()
-		m.Report.plot()
+		if m.testCfg.SystemMode == BoundRPS && m.testCfg.StepRPS != 0 {
+			k, err := m.Report.detectKnee()
+			if err != nil {
+				m.L.Error(err)
+			} else {
+				m.Knee = k
+				m.L.Infof("knee: %s", m.Knee)
+			}
+		}
+		m.Report.plot(m.Knee)
+		m.Report.writeDashboard(m.RunMetrics, m.LabelMetrics, m.Knee, m.SLO, [2]string{"Nodes", strings.Join(m.testCfg.ClusterOptions.Nodes, ", ")})
+		maxRPS = m.Report.maxRPS()
+	}
+	if m.testCfg.ReportOptions.JUnit {
+		writeJUnit(m.JUnit(), m.JUnitPath(), m.L)
+	}
+	manifest := m.Manifest(maxRPS)
+	writeManifest(manifest, m.ManifestPath(), m.L)
+	if m.testCfg.ReportOptions.CSV && m.testCfg.ReportOptions.Markdown {
+		m.Report.writeMarkdownReport(manifest, m.MarkdownReportPath())
+	}
+	if m.testCfg.Slack != nil {
+		postSlack(m.testCfg.Slack, m.Report, manifest, m.L)
 	}
 }
 
 func (m *ClusterClient) collectResults() {
 	for {
 		select {
-		case res := <-m.Results:
+		case nodeRes := <-m.Results:
+			res := nodeRes.Results
 			token := res[0].AttackToken
 			tick := res[0].AttackToken.Tick
 			if _, ok := m.clusterTickMetrics[tick]; !ok {
@@ -169,17 +276,46 @@ func (m *ClusterClient) collectResults() {
 					Samples: make([][]AttackResult, 0),
 					Metrics: NewMetrics(),
 				}
+				m.clusterTickMetrics[tick].Metrics.SLO = NewSLOMetrics(m.testCfg.SLO)
 			}
 			currentTickMetrics := m.clusterTickMetrics[tick]
 			currentTickMetrics.Samples = append(currentTickMetrics.Samples, res)
+			currentTickMetrics.Nodes = append(currentTickMetrics.Nodes, nodeRes.Node)
+			if m.es != nil {
+				for _, s := range res {
+					m.es.Add(nodeRes.Node, s)
+				}
+			}
 			if len(currentTickMetrics.Samples) == len(m.testCfg.ClusterOptions.Nodes) {
 				// aggregate over all ticks across cluster
 				for _, sampleBatch := range currentTickMetrics.Samples {
 					for _, s := range sampleBatch {
 						currentTickMetrics.Metrics.add(s)
+						m.RunMetrics.add(s)
+						if _, ok := m.LabelMetrics[s.DoResult.RequestLabel]; !ok {
+							m.LabelMetrics[s.DoResult.RequestLabel] = NewMetrics()
+						}
+						m.LabelMetrics[s.DoResult.RequestLabel].add(s)
+						if s.DoResult.Error != "" {
+							m.uniqErrors[s.DoResult.Error]++
+						}
 					}
 				}
+				currentTickMetrics.Metrics.TargetRate = float64(token.TargetRPS * len(m.testCfg.ClusterOptions.Nodes))
 				currentTickMetrics.Metrics.update()
+				samples := make([]AttackResult, 0)
+				for _, sampleBatch := range currentTickMetrics.Samples {
+					samples = append(samples, sampleBatch...)
+				}
+				m.labelSteps.addTick(token.Step, tick, token.TargetRPS*len(m.testCfg.ClusterOptions.Nodes), currentTickMetrics.Metrics.Rate, samples)
+				m.RunMetrics.update()
+				for _, lm := range m.LabelMetrics {
+					lm.update()
+				}
+				if m.ticksReported == 0 || currentTickMetrics.Metrics.Success < m.minTickSuccess {
+					m.minTickSuccess = currentTickMetrics.Metrics.Success
+				}
+				m.ticksReported++
 				m.L.Infof(
 					"step: %d, tick: %d, rate [%4f -> %v], perc: 50 [%v] 95 [%v] 99 [%v], # requests [%d], %% success [%d]",
 					token.Step,
@@ -192,8 +328,33 @@ func (m *ClusterClient) collectResults() {
 					currentTickMetrics.Metrics.Requests,
 					currentTickMetrics.Metrics.successLogEntry(),
 				)
+				reportSinks(m.Sinks, SinkTick{
+					Runner:    m.testCfg.Name,
+					Node:      AggregatedNode,
+					Step:      token.Step,
+					Tick:      tick,
+					Attackers: m.testCfg.Attackers,
+					Time:      time.Now(),
+					Metrics:   currentTickMetrics.Metrics,
+				})
+				if m.live != nil {
+					m.publishLiveTick(token, currentTickMetrics)
+				}
+				if s := currentTickMetrics.Metrics.SLO; s != nil {
+					m.SLO.addTick(token.Step, s)
+					m.L.Infof(SLOTickTemplate, s, token.Step, m.SLO.Steps[token.Step])
+				}
 				if m.testCfg.ReportOptions.CSV {
 					m.Report.writePercentilesEntry(res[0], currentTickMetrics.Metrics)
+					samples := make([]AttackResult, 0)
+					for _, sampleBatch := range currentTickMetrics.Samples {
+						samples = append(samples, sampleBatch...)
+					}
+					// nodes scale attackers independently, configured amount is shown
+					m.Report.writeDashboardTick(token, m.testCfg.Attackers, currentTickMetrics.Metrics, samples)
+					m.Report.writeSLOEntry(res[0], currentTickMetrics.Metrics.SLO)
+					m.Report.writeCustomMetricsEntry(res[0], currentTickMetrics.Metrics)
+					m.Report.writeHTTPPhasesEntry(res[0], currentTickMetrics.Metrics)
 				}
 				// shutdown other Nodes if error is present in tick
 				if m.shutdownOnNodeSampleError(currentTickMetrics.Metrics) {
@@ -209,8 +370,24 @@ func (m *ClusterClient) collectResults() {
 	}
 }
 
+// publishLiveTick publishes tick aggregated over the cluster and tick of every node
+func (m *ClusterClient) publishLiveTick(token attackToken, tm *ClusterTickMetrics) {
+	nodeAttackers := m.testCfg.Attackers / len(m.testCfg.ClusterOptions.Nodes)
+	m.live.publish(newLiveTick(AggregatedNode, token, m.testCfg.Attackers, tm.Metrics))
+	for i, sampleBatch := range tm.Samples {
+		nm := NewMetrics()
+		for _, s := range sampleBatch {
+			nm.add(s)
+		}
+		nm.TargetRate = float64(token.TargetRPS)
+		nm.update()
+		m.live.publish(newLiveTick(tm.Nodes[i], token, nodeAttackers, nm))
+	}
+}
+
 func (m *ClusterClient) shutdownOnNodeSampleError(metrics *Metrics) bool {
 	if metrics.Success < m.testCfg.SuccessRatio {
+		m.failureReason = fmt.Sprintf("success ratio threshold reached: %.4f < %.4f", metrics.Success, m.testCfg.SuccessRatio)
 		for idx, c := range m.clients {
 			m.L.Infof("shutting down runner: %d", idx)
 			c.Shutdown()
@@ -220,3 +397,51 @@ func (m *ClusterClient) shutdownOnNodeSampleError(metrics *Metrics) bool {
 	}
 	return false
 }
+
+// Thresholds checks cluster run against success ratio and SLO objectives
+func (m *ClusterClient) Thresholds() []ThresholdOutcome {
+	return thresholds(m.testCfg.SuccessRatio, m.minTickSuccess, m.ticksReported, m.SLO)
+}
+
+// Manifest describes finished cluster run, metrics are aggregated over all nodes
+func (m *ClusterClient) Manifest(maxRPS float64) *RunManifest {
+	host, _ := os.Hostname()
+	res := &RunManifest{
+		Name:      m.testCfg.Name,
+		Config:    redactConfig(m.testCfg),
+		Start:     m.startTime,
+		End:       m.endTime,
+		Host:      host,
+		GoVersion: runtime.Version(),
+		Artifacts: map[string]string{},
+		Summary: RunSummary{
+			Requests:    m.RunMetrics.Requests,
+			Success:     m.RunMetrics.Success,
+			MaxRPS:      maxRPS,
+			Latencies:   m.RunMetrics.Latencies,
+			StatusCodes: m.RunMetrics.StatusCodes,
+			Errors:      m.uniqErrors,
+			Knee:        m.Knee,
+			SLO:         m.SLO,
+		},
+		Thresholds:    m.Thresholds(),
+		FailureReason: m.failureReason,
+	}
+	if m.Report != nil {
+		res.RunID = m.Report.runId
+		res.Artifacts = m.Report.artifacts()
+		if m.testCfg.ReportOptions.Markdown {
+			res.Artifacts["markdown"] = m.MarkdownReportPath()
+		}
+	}
+	if m.testCfg.ReportOptions.JUnit {
+		res.Artifacts["junit"] = m.JUnitPath()
+	}
+	res.check()
+	return res
+}
+
+// MarkdownReportPath path of the latest cluster run markdown report
+func (m *ClusterClient) MarkdownReportPath() string {
+	return path.Join(m.testCfg.ReportOptions.CSVDir, fmt.Sprintf(MarkdownReportFile, m.testCfg.Name))
+}
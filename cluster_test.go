@@ -5,6 +5,12 @@
 package loaderbot
 
 import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -69,6 +75,7 @@ func TestCommonClusterShutdownOnError(t *testing.T) {
 		LogLevel:        "info",
 		ReportOptions: &ReportOptions{
 			Stream: true,
+			CSVDir: "test_csv",
 		},
 		ClusterOptions: &ClusterOptions{
 			Nodes: []string{"localhost:50055", "localhost:50056"},
@@ -76,6 +83,18 @@ func TestCommonClusterShutdownOnError(t *testing.T) {
 	})
 	c.Run()
 	require.Equal(t, true, c.failed)
+	m := c.Manifest(0)
+	require.False(t, m.Passed)
+	require.Contains(t, m.FailureReason, "success ratio threshold reached")
+	require.Equal(t, c.RunMetrics.Requests, m.Summary.Requests)
+	require.NotEmpty(t, m.Summary.Errors)
+	// nodes monitor their generators themselves
+	require.Nil(t, m.Summary.Trustworthy)
+	// manifest is written without csv report
+	written, err := LoadManifest(c.ManifestPath())
+	require.NoError(t, err)
+	require.False(t, written.Passed)
+	require.Equal(t, m.Summary.Requests, written.Summary.Requests)
 }
 
 func TestCommonClusterNodeIsBusy(t *testing.T) {
@@ -111,3 +130,233 @@ func TestCommonClusterNodeIsBusy(t *testing.T) {
 	c2 := NewClusterClient(cfg)
 	require.True(t, c2.failed)
 }
+
+func TestCommonClusterLiveDashboard(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	s1 := RunService("localhost:50058")
+	defer s1.GracefulStop()
+	s2 := RunService("localhost:50059")
+	defer s2.GracefulStop()
+	time.Sleep(1 * time.Second)
+	c := NewClusterClient(&RunnerConfig{
+		TargetUrl:       target.URL,
+		Name:            "live_cluster",
+		InstanceType:    "HTTPAttackerExample",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     4,
+		LogEncoding:     "console",
+		LogLevel:        "info",
+		ReportOptions: &ReportOptions{
+			Stream: true,
+		},
+		ClusterOptions: &ClusterOptions{
+			Nodes: []string{"localhost:50058", "localhost:50059"},
+		},
+		LiveDashboard: &LiveDashboard{Enable: true, Port: 2198},
+	})
+	c.Run()
+	ticks := c.live.published()
+	require.NotEmpty(t, ticks)
+	byNode := make(map[string]int)
+	for _, tick := range ticks {
+		byNode[tick.Node]++
+		if tick.Node == AggregatedNode {
+			require.Equal(t, 10, tick.Attackers)
+		} else {
+			require.Equal(t, 5, tick.Attackers)
+		}
+	}
+	// every aggregated tick is followed by ticks of all nodes
+	require.Len(t, byNode, 3)
+	require.Equal(t, byNode[AggregatedNode], byNode["localhost:50058"])
+	require.Equal(t, byNode[AggregatedNode], byNode["localhost:50059"])
+	require.Equal(t, AggregatedNode, ticks[0].Node)
+}
+
+func TestCommonClusterDashboardAttackers(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	s1 := RunService("localhost:50060")
+	defer s1.GracefulStop()
+	s2 := RunService("localhost:50061")
+	defer s2.GracefulStop()
+	time.Sleep(1 * time.Second)
+	c := NewClusterClient(&RunnerConfig{
+		TargetUrl:       target.URL,
+		Name:            "dashboard_cluster",
+		InstanceType:    "HTTPAttackerExample",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		LogEncoding:     "console",
+		LogLevel:        "info",
+		ReportOptions: &ReportOptions{
+			CSV:     true,
+			PNG:     true,
+			Stream:  true,
+			CSVDir:  "test_csv",
+			HTMLDir: "test_html",
+		},
+		ClusterOptions: &ClusterOptions{
+			Nodes: []string{"localhost:50060", "localhost:50061"},
+		},
+	})
+	c.Run()
+	ticks := c.Report.dashboard.Ticks
+	require.NotEmpty(t, ticks)
+	// configured attackers are split between nodes, dashboard shows the total
+	for _, tick := range ticks {
+		require.Equal(t, 10, tick.Attackers)
+	}
+}
+
+func TestCommonClusterJUnit(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	s1 := RunService("localhost:50062")
+	defer s1.GracefulStop()
+	s2 := RunService("localhost:50063")
+	defer s2.GracefulStop()
+	time.Sleep(1 * time.Second)
+	c := NewClusterClient(&RunnerConfig{
+		TargetUrl:       target.URL,
+		Name:            "junit_cluster",
+		InstanceType:    "HTTPAttackerExample",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		LogEncoding:     "console",
+		LogLevel:        "info",
+		ReportOptions: &ReportOptions{
+			CSV:    false,
+			Stream: true,
+			JUnit:  true,
+			CSVDir: "test_csv",
+		},
+		ClusterOptions: &ClusterOptions{
+			Nodes: []string{"localhost:50062", "localhost:50063"},
+		},
+	})
+	c.Run()
+	data, err := ioutil.ReadFile(c.JUnitPath())
+	require.NoError(t, err)
+	var junit JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &junit))
+	require.Equal(t, "junit_cluster", junit.Suites[0].Name)
+	require.Equal(t, 0, junit.Suites[0].Failures)
+	require.Equal(t, "success_ratio", junit.Suites[0].Cases[0].Name)
+}
+
+func TestCommonClusterNodeConfig(t *testing.T) {
+	cfg := &RunnerConfig{
+		Name:           "node_cfg",
+		Attackers:      10,
+		StartRPS:       10,
+		StepRPS:        4,
+		ReportOptions:  &ReportOptions{CSV: true, JUnit: true},
+		ClusterOptions: &ClusterOptions{Nodes: []string{"localhost:50051", "localhost:50052"}},
+		Slack:          &Slack{WebhookURL: "http://localhost/hook"},
+		LiveDashboard:  &LiveDashboard{Enable: true, Port: 2113},
+		OpenTelemetry:  &OpenTelemetry{Endpoint: "http://localhost:4318"},
+	}
+	c := &ClusterClient{testCfg: cfg, L: NewLogger(&RunnerConfig{LogLevel: "info", LogEncoding: "console"})}
+	nodeCfg := c.configToNodes("localhost:50052")
+	require.Equal(t, 5, nodeCfg.Attackers)
+	require.Equal(t, 2, nodeCfg.StepRPS)
+	// results are reported by cluster client only
+	require.False(t, nodeCfg.ReportOptions.CSV)
+	require.False(t, nodeCfg.ReportOptions.JUnit)
+	require.Nil(t, nodeCfg.Slack)
+	require.Nil(t, nodeCfg.LiveDashboard)
+	require.NotNil(t, cfg.Slack)
+	require.Empty(t, cfg.ClusterOptions.Node)
+	// every node exports otel series with its own node attribute
+	e := newOTelExporter(nodeCfg, c.L)
+	require.Contains(t, e.resource.Attributes, otlpString("loaderbot.node", "localhost:50052"))
+	require.True(t, cfg.ReportOptions.CSV)
+}
+
+func TestCommonClusterSinks(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	mu := &sync.Mutex{}
+	writes := make([][]byte, 0)
+	influx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		writes = append(writes, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer influx.Close()
+	es := newESStandIn()
+	esSrv := httptest.NewServer(es)
+	defer esSrv.Close()
+	slack := newSlackStandIn(0, 0)
+	slackSrv := httptest.NewServer(slack)
+	defer slackSrv.Close()
+	s1 := RunService("localhost:50064")
+	defer s1.GracefulStop()
+	s2 := RunService("localhost:50065")
+	defer s2.GracefulStop()
+	time.Sleep(1 * time.Second)
+	// sinks are configured without defaults
+	c := NewClusterClient(&RunnerConfig{
+		TargetUrl:       target.URL,
+		Name:            "sinks_cluster",
+		InstanceType:    "HTTPAttackerExample",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		LogEncoding:     "console",
+		LogLevel:        "info",
+		ReportOptions: &ReportOptions{
+			Stream: true,
+			CSVDir: "test_csv",
+		},
+		ClusterOptions: &ClusterOptions{
+			Nodes: []string{"localhost:50064", "localhost:50065"},
+		},
+		InfluxDB:      &InfluxDB{URL: influx.URL},
+		Elasticsearch: &Elasticsearch{URL: esSrv.URL},
+		Slack:         &Slack{WebhookURL: slackSrv.URL},
+	})
+	c.Run()
+	require.False(t, c.failed)
+
+	mu.Lock()
+	require.NotEmpty(t, writes)
+	for _, w := range writes {
+		require.True(t, bytes.HasPrefix(w, []byte("loaderbot,runner=sinks_cluster")))
+	}
+	mu.Unlock()
+	es.mu.Lock()
+	require.Equal(t, int(c.RunMetrics.Requests), len(es.docs))
+	for _, d := range es.docs {
+		require.Contains(t, []string{"localhost:50064", "localhost:50065"}, d.Node)
+	}
+	es.mu.Unlock()
+	// nodes don't post to slack
+	slack.mu.Lock()
+	require.Len(t, slack.messages, 1)
+	require.Equal(t, "Load test sinks_cluster: PASSED", slack.messages[0].Text)
+	slack.mu.Unlock()
+}
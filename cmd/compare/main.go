@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/insolar/loaderbot"
+)
+
+// compares candidate runs with baseline, exits with code 1 if regressions are found
+func main() {
+	baseline := flag.String("baseline", "", "baseline percs csv or summary json")
+	candidates := flag.String("candidates", "", "comma separated candidate percs csv or summary json files")
+	alignBy := flag.String("align", loaderbot.AlignByStep, "align runs by step|rps")
+	p50 := flag.Float64("p50", loaderbot.DefaultTolerances.P50, "allowed relative p50 increase")
+	p95 := flag.Float64("p95", loaderbot.DefaultTolerances.P95, "allowed relative p95 increase")
+	p99 := flag.Float64("p99", loaderbot.DefaultTolerances.P99, "allowed relative p99 increase")
+	rps := flag.Float64("rps", loaderbot.DefaultTolerances.RPS, "allowed relative rps decrease")
+	success := flag.Float64("success", loaderbot.DefaultTolerances.Success, "allowed absolute success ratio decrease")
+	baselineRaw := flag.String("baseline-raw", "", "baseline raw requests csv or binary results log, enables per step significance tests")
+	candidatesRaw := flag.String("candidates-raw", "", "comma separated candidates raw requests csv or binary results logs, same order as candidates")
+	alpha := flag.Float64("alpha", 0.05, "significance level")
+	htmlOut := flag.String("html", "comparison.html", "html report file")
+	mdOut := flag.String("md", "comparison.md", "markdown report file")
+	flag.Parse()
+	if *baseline == "" || *candidates == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base, err := loaderbot.LoadRun(*baseline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	runs := make([]*loaderbot.RunData, 0)
+	for _, path := range strings.Split(*candidates, ",") {
+		run, err := loaderbot.LoadRun(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runs = append(runs, run)
+	}
+	c := loaderbot.Compare(base, runs, loaderbot.CompareOptions{
+		AlignBy: *alignBy,
+		Tolerances: loaderbot.Tolerances{
+			P50:     *p50,
+			P95:     *p95,
+			P99:     *p99,
+			RPS:     *rps,
+			Success: *success,
+		},
+	})
+	if *baselineRaw != "" {
+		baseLatencies, err := loaderbot.LoadStepLatencies(*baselineRaw, *alignBy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		candLatencies := make([]map[int][]float64, 0)
+		for _, path := range strings.Split(*candidatesRaw, ",") {
+			l, err := loaderbot.LoadStepLatencies(path, *alignBy)
+			if err != nil {
+				log.Fatal(err)
+			}
+			candLatencies = append(candLatencies, l)
+		}
+		if err := c.ApplySignificance(baseLatencies, candLatencies, loaderbot.SignificanceOptions{Alpha: *alpha}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := c.WriteHTML(*htmlOut); err != nil {
+		log.Fatal(err)
+	}
+	if err := c.WriteMarkdown(*mdOut); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(c.Markdown())
+	if !c.Passed {
+		os.Exit(1)
+	}
+}
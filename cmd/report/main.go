@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/insolar/loaderbot"
+)
+
+// rebuilds percs csv and html chart from recorded raw results
+func main() {
+	results := flag.String("results", "", "raw results binary log (.lbr.gz) or requests csv")
+	name := flag.String("name", "report", "report name")
+	window := flag.Duration("window", time.Second, "aggregation window")
+	labels := flag.String("labels", "", "comma separated request labels to keep, all if empty")
+	from := flag.Duration("from", 0, "skip results which began earlier after run start")
+	to := flag.Duration("to", 0, "skip results which began later after run start, no limit if 0")
+	csvDir := flag.String("csv-dir", "results_csv", "percs csv directory")
+	htmlDir := flag.String("html-dir", "results_html", "html report directory")
+	flag.Parse()
+	if *results == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts := loaderbot.RegenerateOptions{
+		Name:    *name,
+		Window:  *window,
+		From:    *from,
+		To:      *to,
+		CSVDir:  *csvDir,
+		HTMLDir: *htmlDir,
+	}
+	if *labels != "" {
+		opts.Labels = strings.Split(*labels, ",")
+	}
+	report, err := loaderbot.RegenerateReport(*results, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("requests: %d, windows: %d", report.Requests, report.Windows)
+	log.Printf("percs: %s", report.PercsFile)
+	log.Printf("html: %s", report.HTMLFile)
+	if report.Knee != nil {
+		log.Printf("knee: %s", report.Knee)
+	}
+}
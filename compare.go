@@ -0,0 +1,469 @@
+package loaderbot
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/charts"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// AlignByStep compares runs step by step
+	AlignByStep = "step"
+	// AlignByRPS compares runs by target rps level
+	AlignByRPS = "rps"
+)
+
+// RunData per step aggregated results of one run, loaded from percs csv or summary file
+type RunData struct {
+	Name  string        `json:"name"`
+	Steps []StepSummary `json:"steps"`
+}
+
+// StepSummary aggregated metrics of one step, percentiles are means of tick percentiles, ms
+type StepSummary struct {
+	Step      int     `json:"step"`
+	TargetRPS int     `json:"target_rps"`
+	Ticks     int     `json:"ticks"`
+	StartTick int     `json:"start_tick"`
+	RPS       float64 `json:"rps"`
+	P50       float64 `json:"p50"`
+	P95       float64 `json:"p95"`
+	P99       float64 `json:"p99"`
+	Success   float64 `json:"success"`
+}
+
+// LoadRun loads run data from percs csv or json summary file
+func LoadRun(path string) (*RunData, error) {
+	if strings.HasSuffix(path, ".json") {
+		return LoadRunSummary(path)
+	}
+	return LoadRunCSV(path)
+}
+
+// LoadRunCSV aggregates percs csv by steps, csv without step column is aggregated by ticks,
+// run is named after the file
+func LoadRunCSV(path string) (*RunData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	// skip csv header
+	_, _ = reader.Read()
+
+	steps := make(map[int]*StepSummary)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 6 {
+			return nil, errors.New("malformed csv")
+		}
+		values, err := parseFloats(record[1:])
+		if err != nil {
+			return nil, err
+		}
+		tick, rps, p50, p95, p99 := values[0], values[1], values[2], values[3], values[4]
+		step, targetRPS, success := int(tick), 0, 1.0
+		if len(values) >= 8 {
+			step, targetRPS, success = int(values[5]), int(values[6]), values[7]
+		}
+		if _, ok := steps[step]; !ok {
+			steps[step] = &StepSummary{Step: step, TargetRPS: targetRPS, StartTick: int(tick)}
+		}
+		s := steps[step]
+		if int(tick) < s.StartTick {
+			s.StartTick = int(tick)
+		}
+		s.Ticks++
+		s.RPS += rps
+		s.P50 += p50
+		s.P95 += p95
+		s.P99 += p99
+		s.Success += success
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("empty csv, nothing to compare")
+	}
+	run := &RunData{Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	for _, s := range steps {
+		ticks := float64(s.Ticks)
+		s.RPS /= ticks
+		s.P50 /= ticks
+		s.P95 /= ticks
+		s.P99 /= ticks
+		s.Success /= ticks
+		run.Steps = append(run.Steps, *s)
+	}
+	sort.Slice(run.Steps, func(i, j int) bool {
+		return run.Steps[i].Step < run.Steps[j].Step
+	})
+	return run, nil
+}
+
+// LoadRunSummary loads run data saved by SaveRunSummary
+func LoadRunSummary(path string) (*RunData, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run RunData
+	if err := jsoniter.Unmarshal(d, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// SaveRunSummary saves run data, so it can be used as a baseline later
+func SaveRunSummary(run *RunData, path string) error {
+	d, err := jsoniter.MarshalIndent(run, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, d, 0644)
+}
+
+// Tolerances regression tolerances, relative ones are fractions, 0.1 means 10% worse than baseline
+type Tolerances struct {
+	// P50, P95, P99 allowed relative latency increase
+	P50 float64
+	P95 float64
+	P99 float64
+	// RPS allowed relative achieved rate decrease
+	RPS float64
+	// Success allowed absolute success ratio decrease
+	Success float64
+}
+
+// DefaultTolerances tolerances suitable for noisy environments, zero tolerances mean any change is a regression
+var DefaultTolerances = Tolerances{
+	P50:     0.1,
+	P95:     0.15,
+	P99:     0.2,
+	RPS:     0.05,
+	Success: 0.01,
+}
+
+// CompareOptions comparison options
+type CompareOptions struct {
+	// AlignBy AlignByStep or AlignByRPS, default is AlignByStep
+	AlignBy    string
+	Tolerances Tolerances
+}
+
+// StepDelta candidate step compared with baseline step
+type StepDelta struct {
+	Key       int
+	Baseline  StepSummary
+	Candidate StepSummary
+	// P50, P95, P99, RPS relative changes, Success absolute change
+	P50     float64
+	P95     float64
+	P99     float64
+	RPS     float64
+	Success float64
+	// Regressions describe which tolerances are exceeded
+	Regressions []string
+	// Insignificant latency regressions which are not statistically significant, see ApplySignificance
+	Insignificant []string
+	// Missing baseline step has no candidate step, it is a regression
+	Missing bool
+	// Significance statistical comparison of step raw latencies, nil if not applied
+	Significance *SignificanceResult
+
+	// quantiles of latency regressions, they go first in Regressions
+	latencyQuantiles []float64
+}
+
+// RunComparison candidate run compared with baseline
+type RunComparison struct {
+	Candidate *RunData
+	Steps     []StepDelta
+	Passed    bool
+}
+
+// Comparison result of comparing candidate runs with baseline
+type Comparison struct {
+	Baseline *RunData
+	Runs     []RunComparison
+	Options  CompareOptions
+	// Passed false if any candidate has regressions
+	Passed bool
+}
+
+// Compare compares every candidate run with baseline, aligning them by step or rps level
+func Compare(baseline *RunData, candidates []*RunData, opts CompareOptions) *Comparison {
+	if opts.AlignBy == "" {
+		opts.AlignBy = AlignByStep
+	}
+	c := &Comparison{
+		Baseline: baseline,
+		Options:  opts,
+		Passed:   true,
+	}
+	baseSteps := alignSteps(baseline, opts.AlignBy)
+	for _, cand := range candidates {
+		rc := RunComparison{Candidate: cand, Passed: true}
+		candSteps := alignSteps(cand, opts.AlignBy)
+		for _, key := range sortedKeys(baseSteps) {
+			var d StepDelta
+			if cs, ok := candSteps[key]; ok {
+				d = stepDelta(key, baseSteps[key], cs, opts.Tolerances)
+			} else {
+				d = StepDelta{
+					Key:         key,
+					Baseline:    baseSteps[key],
+					Missing:     true,
+					Regressions: []string{fmt.Sprintf("%s %d missing in candidate", opts.AlignBy, key)},
+				}
+			}
+			if len(d.Regressions) > 0 {
+				rc.Passed = false
+				c.Passed = false
+			}
+			rc.Steps = append(rc.Steps, d)
+		}
+		c.Runs = append(c.Runs, rc)
+	}
+	return c
+}
+
+func alignSteps(run *RunData, alignBy string) map[int]StepSummary {
+	res := make(map[int]StepSummary)
+	for _, s := range run.Steps {
+		key := s.Step
+		if alignBy == AlignByRPS {
+			key = s.TargetRPS
+		}
+		res[key] = s
+	}
+	return res
+}
+
+func sortedKeys(m map[int]StepSummary) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func stepDelta(key int, base, cand StepSummary, t Tolerances) StepDelta {
+	d := StepDelta{
+		Key:       key,
+		Baseline:  base,
+		Candidate: cand,
+		P50:       relativeChange(base.P50, cand.P50),
+		P95:       relativeChange(base.P95, cand.P95),
+		P99:       relativeChange(base.P99, cand.P99),
+		RPS:       relativeChange(base.RPS, cand.RPS),
+		Success:   cand.Success - base.Success,
+	}
+	if d.P50 > t.P50 {
+		d.Regressions = append(d.Regressions, fmt.Sprintf("p50 %.2fms -> %.2fms (%+.2f%%)", base.P50, cand.P50, d.P50*100))
+		d.latencyQuantiles = append(d.latencyQuantiles, 0.5)
+	}
+	if d.P95 > t.P95 {
+		d.Regressions = append(d.Regressions, fmt.Sprintf("p95 %.2fms -> %.2fms (%+.2f%%)", base.P95, cand.P95, d.P95*100))
+		d.latencyQuantiles = append(d.latencyQuantiles, 0.95)
+	}
+	if d.P99 > t.P99 {
+		d.Regressions = append(d.Regressions, fmt.Sprintf("p99 %.2fms -> %.2fms (%+.2f%%)", base.P99, cand.P99, d.P99*100))
+		d.latencyQuantiles = append(d.latencyQuantiles, 0.99)
+	}
+	if -d.RPS > t.RPS {
+		d.Regressions = append(d.Regressions, fmt.Sprintf("rps %.2f -> %.2f (%+.2f%%)", base.RPS, cand.RPS, d.RPS*100))
+	}
+	if -d.Success > t.Success {
+		d.Regressions = append(d.Regressions, fmt.Sprintf("success %.4f -> %.4f", base.Success, cand.Success))
+	}
+	return d
+}
+
+// ApplySignificance tests raw latencies of every candidate step against the same baseline step,
+// latencies are keyed the same way steps are aligned, see LoadStepLatencies,
+// latency regression of a step is not counted if Mann-Whitney p-value is not below Alpha
+// or bootstrap interval of its quantile difference is not above zero
+func (c *Comparison) ApplySignificance(baseline map[int][]float64, candidates []map[int][]float64, opts SignificanceOptions) error {
+	if len(candidates) != len(c.Runs) {
+		return fmt.Errorf("got latencies of %d candidates, compared %d", len(candidates), len(c.Runs))
+	}
+	c.Passed = true
+	for i := range c.Runs {
+		rc := &c.Runs[i]
+		rc.Passed = true
+		for j := range rc.Steps {
+			d := &rc.Steps[j]
+			base, cand := baseline[d.Key], candidates[i][d.Key]
+			if !d.Missing && len(base) > 0 && len(cand) > 0 {
+				d.Significance = CompareLatencies(base, cand, opts)
+				d.applySignificance()
+			}
+			if len(d.Regressions) > 0 {
+				rc.Passed = false
+			}
+		}
+		c.Passed = c.Passed && rc.Passed
+	}
+	return nil
+}
+
+// applySignificance moves latency regressions to Insignificant unless Mann-Whitney test finds candidate latencies
+// significantly greater and the quantile is significantly slower
+func (d *StepDelta) applySignificance() {
+	regressions := make([]string, 0, len(d.Regressions))
+	for i, r := range d.Regressions {
+		if i < len(d.latencyQuantiles) && !(d.Significance.Regression && d.Significance.slower(d.latencyQuantiles[i])) {
+			d.Insignificant = append(d.Insignificant, r)
+			continue
+		}
+		regressions = append(regressions, r)
+	}
+	d.Regressions = regressions
+	d.latencyQuantiles = nil
+}
+
+func relativeChange(base, cand float64) float64 {
+	if base == 0 {
+		if cand == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (cand - base) / base
+}
+
+// Markdown renders comparison as markdown tables, one table per candidate
+func (c *Comparison) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Comparison with baseline `%s`: %s\n\n", c.Baseline.Name, verdict(c.Passed))
+	for _, rc := range c.Runs {
+		fmt.Fprintf(&b, "### `%s`: %s\n\n", rc.Candidate.Name, verdict(rc.Passed))
+		fmt.Fprintf(&b, "| %s | rps | p50, ms | p95, ms | p99, ms | success | regressions |\n", c.Options.AlignBy)
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, d := range rc.Steps {
+			if d.Missing {
+				fmt.Fprintf(&b, "| %d | - | - | - | - | - | %s |\n", d.Key, regressionsMarkdown(d))
+				continue
+			}
+			fmt.Fprintf(&b, "| %d | %.2f (%+.2f%%) | %.2f (%+.2f%%) | %.2f (%+.2f%%) | %.2f (%+.2f%%) | %.4f (%+.4f) | %s |\n",
+				d.Key,
+				d.Candidate.RPS, d.RPS*100,
+				d.Candidate.P50, d.P50*100,
+				d.Candidate.P95, d.P95*100,
+				d.Candidate.P99, d.P99*100,
+				d.Candidate.Success, d.Success,
+				regressionsMarkdown(d),
+			)
+		}
+		b.WriteString("\n")
+		for _, d := range rc.Steps {
+			if d.Significance != nil {
+				fmt.Fprintf(&b, "%s %d: %s", c.Options.AlignBy, d.Key, d.Significance.Markdown())
+			}
+		}
+	}
+	return b.String()
+}
+
+// WriteMarkdown writes markdown comparison report
+func (c *Comparison) WriteMarkdown(path string) error {
+	return ioutil.WriteFile(path, []byte(c.Markdown()), 0644)
+}
+
+// WriteHTML writes comparison report with overlaid charts of all runs for every metric
+func (c *Comparison) WriteHTML(path string) error {
+	runs := []*RunData{c.Baseline}
+	for _, rc := range c.Runs {
+		runs = append(runs, rc.Candidate)
+	}
+	page := charts.NewPage()
+	metrics := []struct {
+		name  string
+		value func(s StepSummary) float64
+	}{
+		{"rps", func(s StepSummary) float64 { return s.RPS }},
+		{"p50, ms", func(s StepSummary) float64 { return s.P50 }},
+		{"p95, ms", func(s StepSummary) float64 { return s.P95 }},
+		{"p99, ms", func(s StepSummary) float64 { return s.P99 }},
+		{"success", func(s StepSummary) float64 { return s.Success }},
+	}
+	keys := sortedKeys(alignSteps(c.Baseline, c.Options.AlignBy))
+	for _, m := range metrics {
+		line := charts.NewLine()
+		line.SetGlobalOptions(
+			charts.TitleOpts{Title: fmt.Sprintf("%s: %s", m.name, verdict(c.Passed))},
+			charts.XAxisOpts{Name: c.Options.AlignBy},
+			charts.YAxisOpts{Name: m.name},
+		)
+		line.AddXAxis(keys)
+		for i, run := range runs {
+			steps := alignSteps(run, c.Options.AlignBy)
+			values := make([]interface{}, len(keys))
+			for j, k := range keys {
+				if s, ok := steps[k]; ok {
+					values[j] = m.value(s)
+				} else {
+					values[j] = "-"
+				}
+			}
+			name := run.Name
+			if i == 0 {
+				name = "baseline: " + name
+			} else {
+				name = strconv.Itoa(i) + ": " + name
+			}
+			line.AddYAxis(name, values)
+		}
+		page.Add(line)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return page.Render(f)
+}
+
+func regressionsMarkdown(d StepDelta) string {
+	res := append([]string{}, d.Regressions...)
+	for _, r := range d.Insignificant {
+		res = append(res, fmt.Sprintf("~~%s~~ not significant", r))
+	}
+	return strings.Join(res, ", ")
+}
+
+func verdict(passed bool) string {
+	if passed {
+		return "PASSED"
+	}
+	return "FAILED"
+}
+
+func parseFloats(record []string) ([]float64, error) {
+	res := make([]float64, 0, len(record))
+	for _, v := range record {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
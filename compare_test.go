@@ -0,0 +1,87 @@
+package loaderbot
+
+import (
+	"encoding/csv"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePercsCsv(t *testing.T, name string, records [][]string) string {
+	dir := "test_csv"
+	_ = os.Mkdir(dir, os.ModePerm)
+	p := path.Join(dir, name)
+	f := CreateFileOrReplace(p)
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write(PercsCsvHeader)
+	require.NoError(t, w.WriteAll(records))
+	return p
+}
+
+func TestCommonCompareRuns(t *testing.T) {
+	base := writePercsCsv(t, "baseline.csv", [][]string{
+		{"r", "1", "100", "10", "20", "30", "1", "100", "1"},
+		{"r", "2", "100", "10", "20", "30", "1", "100", "1"},
+		{"r", "3", "200", "20", "40", "60", "2", "200", "1"},
+	})
+	same := writePercsCsv(t, "same.csv", [][]string{
+		{"r", "1", "100", "10", "21", "30", "1", "100", "1"},
+		{"r", "2", "200", "20", "40", "60", "2", "200", "1"},
+	})
+	slow := writePercsCsv(t, "slow.csv", [][]string{
+		{"r", "1", "100", "10", "20", "30", "1", "100", "1"},
+		{"r", "2", "180", "40", "80", "120", "2", "200", "0.9"},
+	})
+	baseRun, err := LoadRun(base)
+	require.NoError(t, err)
+	require.Len(t, baseRun.Steps, 2)
+	require.Equal(t, 2, baseRun.Steps[0].Ticks)
+
+	sameRun, err := LoadRun(same)
+	require.NoError(t, err)
+	slowRun, err := LoadRun(slow)
+	require.NoError(t, err)
+
+	c := Compare(baseRun, []*RunData{sameRun}, CompareOptions{Tolerances: DefaultTolerances})
+	require.True(t, c.Passed)
+
+	c = Compare(baseRun, []*RunData{sameRun, slowRun}, CompareOptions{AlignBy: AlignByRPS, Tolerances: DefaultTolerances})
+	require.False(t, c.Passed)
+	require.True(t, c.Runs[0].Passed)
+	require.False(t, c.Runs[1].Passed)
+	require.Empty(t, c.Runs[1].Steps[0].Regressions)
+	require.Len(t, c.Runs[1].Steps[1].Regressions, 5)
+	require.Contains(t, c.Markdown(), "FAILED")
+	require.NoError(t, c.WriteHTML(path.Join("test_csv", "comparison.html")))
+}
+
+func TestCommonCompareMissingSteps(t *testing.T) {
+	base := &RunData{Name: "base", Steps: []StepSummary{
+		{Step: 1, TargetRPS: 100, RPS: 100, P50: 10, P95: 20, P99: 30, Success: 1},
+		{Step: 2, TargetRPS: 200, RPS: 200, P50: 20, P95: 40, P99: 60, Success: 1},
+		{Step: 3, TargetRPS: 300, RPS: 300, P50: 30, P95: 60, P99: 90, Success: 1},
+	}}
+	short := &RunData{Name: "short", Steps: base.Steps[:1]}
+	c := Compare(base, []*RunData{short}, CompareOptions{Tolerances: DefaultTolerances})
+	require.False(t, c.Passed)
+	require.False(t, c.Runs[0].Passed)
+	require.Len(t, c.Runs[0].Steps, 3)
+	require.Empty(t, c.Runs[0].Steps[0].Regressions)
+	require.True(t, c.Runs[0].Steps[1].Missing)
+	require.Equal(t, []string{"step 2 missing in candidate"}, c.Runs[0].Steps[1].Regressions)
+	require.Equal(t, []string{"step 3 missing in candidate"}, c.Runs[0].Steps[2].Regressions)
+	require.Contains(t, c.Markdown(), "| 3 | - | - | - | - | - | step 3 missing in candidate |")
+}
+
+func TestCommonCompareRunSummary(t *testing.T) {
+	run, err := LoadRun("example_csv_data/percs.csv")
+	require.NoError(t, err)
+	p := path.Join(os.TempDir(), "loaderbot_summary.json")
+	require.NoError(t, SaveRunSummary(run, p))
+	loaded, err := LoadRun(p)
+	require.NoError(t, err)
+	require.Equal(t, run, loaded)
+}
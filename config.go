@@ -70,15 +70,167 @@ type RunnerConfig struct {
 	ClusterOptions *ClusterOptions
 	// Prometheus config
 	Prometheus *Prometheus
+	// GeneratorLimits load generator saturation thresholds
+	GeneratorLimits *GeneratorLimits
+	// SLO service level objectives, compliance is computed per tick, step and run
+	SLO *SLO
+	// LiveDashboard serves dashboard with tick metrics updated in real time during the run
+	LiveDashboard *LiveDashboard
+	// TUI shows run state in terminal instead of tick log lines, ignored if stdout is not a terminal
+	TUI bool
+	// Slack posts run summary and charts to slack compatible incoming webhook when run ends
+	Slack *Slack
+	// OpenTelemetry exports tick metrics and sampled attack spans to OTLP/HTTP collector
+	OpenTelemetry *OpenTelemetry
+	// InfluxDB writes tick metrics in line protocol over http or udp
+	InfluxDB *InfluxDB
+	// StatsD sends tick metrics as statsd or dogstatsd gauges over udp
+	StatsD *StatsD
+	// Elasticsearch ships raw results to elasticsearch bulk api
+	Elasticsearch *Elasticsearch
 }
 
 type Prometheus struct {
 	Enable bool
 	Port   int
+	// Buckets request latency histogram buckets, seconds, prometheus.DefBuckets if empty
+	Buckets []float64
+	// Pushgateway url, metrics are pushed to it periodically and with run summary on completion,
+	// job is run name, grouping labels are scalar Metadata values, metrics are pushed even if Enable is false
+	Pushgateway string
+	// PushIntervalSec push period, default is 5
+	PushIntervalSec int
+}
+
+// LiveDashboard live dashboard http server config, default port is 2113
+type LiveDashboard struct {
+	Enable bool
+	Port   int
+}
+
+// Slack incoming webhook delivery config
+type Slack struct {
+	// WebhookURL slack compatible incoming webhook
+	WebhookURL string
+	// OnlyOnFailure posts only runs which failed a threshold
+	OnlyOnFailure bool
+	// ImagesURL base url where csv dir is published, charts are shown as image blocks linked to it
+	ImagesURL string
+	// BotToken bot token with files:write scope, if set and ImagesURL is empty charts are uploaded to Channel with files.getUploadURLExternal and files.completeUploadExternal,
+	// incoming webhooks accept json only, so charts are not posted if neither is set
+	BotToken string
+	// Channel id of channel charts are uploaded to
+	Channel string
+	// APIURL slack web api base url, default is https://slack.com/api
+	APIURL string
+	// TimeoutSec timeout of one post attempt, default is 10
+	TimeoutSec int
+	// Retries attempts after failed post, 0 disables retries
+	Retries int
+	// RetryIntervalMs delay between attempts, default is 1000
+	RetryIntervalMs int
+}
+
+// OpenTelemetry OTLP/HTTP json export config
+type OpenTelemetry struct {
+	// Endpoint collector base url, e.g. http://localhost:4318, /v1/metrics and /v1/traces are appended
+	Endpoint string
+	// Headers sent with every export request, e.g. auth
+	Headers map[string]string
+	// SpanSampleRatio share of attacks exported as spans, 0 disables spans,
+	// traceparent header is injected into requests of sampled attacks made with runner http clients
+	SpanSampleRatio float64
+	// ExportIntervalSec export period, default is 5
+	ExportIntervalSec int
+	// TimeoutSec timeout of one export request, default is 10
+	TimeoutSec int
+}
+
+// InfluxDB line protocol sink config
+type InfluxDB struct {
+	// URL write endpoint, e.g. http://localhost:8086/write?db=loaderbot
+	// or http://localhost:8086/api/v2/write?org=org&bucket=loaderbot
+	URL string
+	// UDPAddr udp listener host:port, used if URL is empty
+	UDPAddr string
+	// Token v2 api token
+	Token string
+	// Measurement default is loaderbot
+	Measurement string
+	// TimeoutSec timeout of one http write, default is 10
+	TimeoutSec int
+}
+
+// StatsD udp sink config
+type StatsD struct {
+	// Addr agent host:port
+	Addr string
+	// Prefix of metric names, default is "loaderbot."
+	Prefix string
+	// DogStatsD sends runner, node and step as tags instead of metric name parts
+	DogStatsD bool
+	// Tags constant tags, DogStatsD only
+	Tags map[string]string
+}
+
+// Elasticsearch bulk export config, export never blocks results collection,
+// results are dropped and counted when queue is full
+type Elasticsearch struct {
+	// URL cluster url, e.g. http://localhost:9200
+	URL string
+	// Index documents index, default is loaderbot-results
+	Index string
+	// Username and Password basic auth
+	Username string
+	Password string
+	// APIKey used instead of basic auth if set
+	APIKey string
+	// BatchSize documents per bulk request, default is 1000
+	BatchSize int
+	// FlushIntervalMs max time result waits in incomplete batch, default is 1000
+	FlushIntervalMs int
+	// QueueSize results buffered while bulk requests are in progress, default is 100000
+	QueueSize int
+	// Workers concurrent bulk requests, default is 1
+	Workers int
+	// TimeoutSec timeout of one bulk request, default is 10
+	TimeoutSec int
+	// Retries attempts after failed bulk request, 0 disables retries
+	Retries int
+}
+
+// GeneratorLimits thresholds after which load generator itself is considered a bottleneck
+type GeneratorLimits struct {
+	// CPUPercent process cpu usage normalized by all cores, default is 90
+	CPUPercent float64
+	// QueueFillRatio results queue fill ratio, default is 0.8
+	QueueFillRatio float64
+	// FDFillRatio open file descriptors to limit ratio, default is 0.9
+	FDFillRatio float64
+	// GCPauseMs max gc pause during sample interval, default is 100
+	GCPauseMs int64
+}
+
+// SLO service level objectives
+type SLO struct {
+	// Objectives latency objectives, e.g. 99% of requests under 300ms, failed requests are never good
+	Objectives []LatencyObjective
+	// ApdexTMs Apdex satisfied threshold, requests up to 4T are tolerating, Apdex is not computed if 0
+	ApdexTMs int64
+}
+
+// LatencyObjective Target ratio of successful requests must be faster than ThresholdMs
+type LatencyObjective struct {
+	// Target ratio of good requests, interval of values = (0, 1]
+	Target float64
+	// ThresholdMs latency threshold
+	ThresholdMs int64
 }
 
 type ClusterOptions struct {
 	Nodes []string
+	// Node address of the node config is sent to, set by cluster client
+	Node string
 }
 
 // ReportOptions reporting options
@@ -89,8 +241,17 @@ type ReportOptions struct {
 	CSVDir string
 	// CSV dumps requests/responses data
 	CSV bool
-	// PNG creates percentiles graph
+	// PNG creates percentiles graph, html report and dashboard, requires CSV
 	PNG bool
+	// RawResults raw results log format, requests csv by default,
+	// compressed binary log is much smaller and faster at high rates, see ReadResultsLog
+	RawResults RawResultsFormat
+	// JUnit writes thresholds, SLO and success ratio checks as junit xml for CI, to CSVDir even if CSV is disabled
+	JUnit bool
+	// Markdown writes run summary report to post on pull requests, requires CSV
+	Markdown bool
+	// Baseline percs csv or json summary of a baseline run, markdown report compares run with it if set
+	Baseline string
 	// Stream streams raw and tick aggregated data back to client in cluster mode
 	Stream bool
 }
@@ -136,6 +297,71 @@ func (c *RunnerConfig) DefaultCfgValues() {
 	if c.Prometheus != nil && c.Prometheus.Port == 0 {
 		c.Prometheus.Port = 2112
 	}
+	if c.Prometheus != nil && c.Prometheus.PushIntervalSec == 0 {
+		c.Prometheus.PushIntervalSec = 5
+	}
+	if c.LiveDashboard != nil && c.LiveDashboard.Port == 0 {
+		c.LiveDashboard.Port = 2113
+	}
+	if c.Slack != nil && c.Slack.TimeoutSec == 0 {
+		c.Slack.TimeoutSec = 10
+	}
+	if c.Slack != nil && c.Slack.RetryIntervalMs == 0 {
+		c.Slack.RetryIntervalMs = 1000
+	}
+	if c.Slack != nil && c.Slack.APIURL == "" {
+		c.Slack.APIURL = "https://slack.com/api"
+	}
+	if c.OpenTelemetry != nil && c.OpenTelemetry.ExportIntervalSec == 0 {
+		c.OpenTelemetry.ExportIntervalSec = 5
+	}
+	if c.OpenTelemetry != nil && c.OpenTelemetry.TimeoutSec == 0 {
+		c.OpenTelemetry.TimeoutSec = 10
+	}
+	if c.InfluxDB != nil && c.InfluxDB.Measurement == "" {
+		c.InfluxDB.Measurement = "loaderbot"
+	}
+	if c.InfluxDB != nil && c.InfluxDB.TimeoutSec == 0 {
+		c.InfluxDB.TimeoutSec = 10
+	}
+	if c.StatsD != nil && c.StatsD.Prefix == "" {
+		c.StatsD.Prefix = "loaderbot."
+	}
+	if e := c.Elasticsearch; e != nil {
+		if e.Index == "" {
+			e.Index = "loaderbot-results"
+		}
+		if e.BatchSize == 0 {
+			e.BatchSize = 1000
+		}
+		if e.FlushIntervalMs == 0 {
+			e.FlushIntervalMs = 1000
+		}
+		if e.QueueSize == 0 {
+			e.QueueSize = 100000
+		}
+		if e.Workers == 0 {
+			e.Workers = 1
+		}
+		if e.TimeoutSec == 0 {
+			e.TimeoutSec = 10
+		}
+	}
+	if c.GeneratorLimits == nil {
+		c.GeneratorLimits = &GeneratorLimits{}
+	}
+	if c.GeneratorLimits.CPUPercent == 0 {
+		c.GeneratorLimits.CPUPercent = 90
+	}
+	if c.GeneratorLimits.QueueFillRatio == 0 {
+		c.GeneratorLimits.QueueFillRatio = 0.8
+	}
+	if c.GeneratorLimits.FDFillRatio == 0 {
+		c.GeneratorLimits.FDFillRatio = 0.9
+	}
+	if c.GeneratorLimits.GCPauseMs == 0 {
+		c.GeneratorLimits.GCPauseMs = 100
+	}
 	if c.SystemMode == BoundRPSAutoscale {
 		if c.AttackersScaleAmount == 0 {
 			c.AttackersScaleAmount = 100
@@ -166,5 +392,51 @@ func (c RunnerConfig) validate() (list []string) {
 	if c.TestTimeSec <= 0 {
 		list = append(list, "please set test time rps > 0, seconds")
 	}
+	if c.ReportOptions != nil && c.ReportOptions.Markdown && !c.ReportOptions.CSV {
+		list = append(list, "please enable csv report, markdown report is built from percs log")
+	}
+	if c.SLO != nil {
+		if c.SLO.ApdexTMs < 0 {
+			list = append(list, "please set apdex T >= 0, ms")
+		}
+		for _, o := range c.SLO.Objectives {
+			if o.Target <= 0 || o.Target > 1 {
+				list = append(list, "please set slo objective target in (0, 1]")
+			}
+			if o.ThresholdMs <= 0 {
+				list = append(list, "please set slo objective threshold > 0, ms")
+			}
+		}
+	}
+	if c.Slack != nil {
+		if c.Slack.WebhookURL == "" {
+			list = append(list, "please set slack webhook url")
+		}
+		if c.Slack.Retries < 0 {
+			list = append(list, "please set slack retries >= 0")
+		}
+	}
+	if c.OpenTelemetry != nil {
+		if c.OpenTelemetry.Endpoint == "" {
+			list = append(list, "please set opentelemetry collector endpoint")
+		}
+		if c.OpenTelemetry.SpanSampleRatio < 0 || c.OpenTelemetry.SpanSampleRatio > 1 {
+			list = append(list, "please set opentelemetry span sample ratio in [0, 1]")
+		}
+	}
+	if c.InfluxDB != nil && c.InfluxDB.URL == "" && c.InfluxDB.UDPAddr == "" {
+		list = append(list, "please set influxdb url or udp address")
+	}
+	if c.StatsD != nil && c.StatsD.Addr == "" {
+		list = append(list, "please set statsd address")
+	}
+	if e := c.Elasticsearch; e != nil {
+		if e.URL == "" {
+			list = append(list, "please set elasticsearch url")
+		}
+		if e.BatchSize < 0 || e.QueueSize < 0 || e.Workers < 0 || e.Retries < 0 {
+			list = append(list, "please set elasticsearch batch size, queue size, workers and retries >= 0")
+		}
+	}
 	return
 }
@@ -0,0 +1,129 @@
+package loaderbot
+
+import (
+	"sort"
+	"time"
+
+	"github.com/streadway/quantile"
+)
+
+// CustomMetricType type of user defined metric emitted from Attack.Do
+type CustomMetricType int
+
+const (
+	// CustomCounter values are summed inside a tick
+	CustomCounter CustomMetricType = iota
+	// CustomGauge values are aggregated as last/min/max/mean inside a tick
+	CustomGauge
+	// CustomTiming values are durations in milliseconds, percentiles are computed inside a tick
+	CustomTiming
+)
+
+func (t CustomMetricType) String() string {
+	switch t {
+	case CustomCounter:
+		return "counter"
+	case CustomGauge:
+		return "gauge"
+	case CustomTiming:
+		return "timing"
+	default:
+		return "unknown"
+	}
+}
+
+// CustomMetric user defined value attached to DoResult
+type CustomMetric struct {
+	Name  string
+	Type  CustomMetricType
+	Value float64
+}
+
+// Counter attaches counter value to the result, e.g. items returned
+func (d *DoResult) Counter(name string, value float64) {
+	d.CustomMetrics = append(d.CustomMetrics, CustomMetric{Name: name, Type: CustomCounter, Value: value})
+}
+
+// Gauge attaches gauge value to the result, e.g. queue depth from response header
+func (d *DoResult) Gauge(name string, value float64) {
+	d.CustomMetrics = append(d.CustomMetrics, CustomMetric{Name: name, Type: CustomGauge, Value: value})
+}
+
+// Timing attaches timing value to the result, stored in milliseconds
+func (d *DoResult) Timing(name string, value time.Duration) {
+	d.CustomMetrics = append(d.CustomMetrics, CustomMetric{Name: name, Type: CustomTiming, Value: durationMs(value)})
+}
+
+// CustomMetricSummary aggregated values of one custom metric inside a tick
+type CustomMetricSummary struct {
+	Name string           `json:"name"`
+	Type CustomMetricType `json:"type"`
+	// Count is the number of values emitted
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+	Last  float64 `json:"last"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	// P50, P95, P99 are computed for timings only
+	P50 float64 `json:"50th"`
+	P95 float64 `json:"95th"`
+	P99 float64 `json:"99th"`
+
+	estimator *quantile.Estimator
+}
+
+func newCustomMetricSummary(name string, typ CustomMetricType) *CustomMetricSummary {
+	s := &CustomMetricSummary{Name: name, Type: typ}
+	if typ == CustomTiming {
+		s.estimator = quantile.New(
+			quantile.Known(0.50, 0.01),
+			quantile.Known(0.95, 0.001),
+			quantile.Known(0.99, 0.0005),
+		)
+	}
+	return s
+}
+
+func (s *CustomMetricSummary) add(v float64) {
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+	s.Last = v
+	if s.estimator != nil {
+		s.estimator.Add(v)
+	}
+}
+
+func (s *CustomMetricSummary) update() {
+	if s.Count == 0 {
+		return
+	}
+	s.Mean = s.Sum / float64(s.Count)
+	if s.estimator != nil {
+		s.P50 = s.estimator.Get(0.50)
+		s.P95 = s.estimator.Get(0.95)
+		s.P99 = s.estimator.Get(0.99)
+	}
+}
+
+// sortedCustomMetrics returns custom metrics summaries sorted by name
+func sortedCustomMetrics(m map[string]*CustomMetricSummary) []*CustomMetricSummary {
+	res := make([]*CustomMetricSummary, 0, len(m))
+	for _, s := range m {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,425 @@
+package loaderbot
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wcharczuk/go-chart"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+const (
+	// DashboardFile self-contained html dashboard
+	DashboardFile = "dashboard_%s_%s_%d.html"
+)
+
+// latencyHistogramBuckets upper bounds of latency histogram buckets, ms, the last bucket is unbounded
+var latencyHistogramBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+// DashboardTick tick data shown on dashboard
+type DashboardTick struct {
+	Tick      int
+	Step      int
+	TargetRPS int
+	Attackers int
+	Rate      float64
+	Requests  uint64
+	Success   float64
+	// P50, P95, P99 latency percentiles, ms
+	P50 float64
+	P95 float64
+	P99 float64
+	// StatusCodes responses by status code
+	StatusCodes map[string]int
+	// ErrorCategories failed requests by category: timeout, connection, status, other
+	ErrorCategories map[string]int
+	// LabelP99 p99 latency by request label, ms
+	LabelP99 map[string]float64
+}
+
+// Dashboard collects run data by ticks and renders self-contained html report
+type Dashboard struct {
+	Name  string
+	Ticks []DashboardTick
+	// Run metrics of all reported results, set from runner metrics when dashboard is written
+	Run *Metrics
+	// Labels metrics of all reported results by request label, set from runner metrics when dashboard is written
+	Labels map[string]*Metrics
+	// Histogram latency distribution over latencyHistogramBuckets
+	Histogram []uint64
+	// Heatmap latency distribution by second
+	Heatmap *LatencyHeatmap
+
+	start   time.Time
+	summary [][2]string
+}
+
+// NewDashboard creates empty dashboard
+func NewDashboard(name string) *Dashboard {
+	return &Dashboard{
+		Name:      name,
+		Run:       NewMetrics(),
+		Labels:    make(map[string]*Metrics),
+		Histogram: make([]uint64, len(latencyHistogramBuckets)+1),
+		Heatmap:   NewLatencyHeatmap(latencyHistogramBuckets),
+		start:     time.Now(),
+	}
+}
+
+// errorCategory groups failed requests, empty if request succeeded
+func errorCategory(res AttackResult) string {
+	code := res.DoResult.StatusCode
+	e := strings.ToLower(res.DoResult.Error)
+	switch {
+	case e == "":
+		if code != 0 && (code < 200 || code >= 400) {
+			return "status"
+		}
+		return ""
+	case strings.Contains(e, "timeout") || strings.Contains(e, "deadline"):
+		return "timeout"
+	case strings.Contains(e, "connection") || strings.Contains(e, "eof") || strings.Contains(e, "dial"):
+		return "connection"
+	default:
+		return "other"
+	}
+}
+
+// addTick adds reported tick with its results
+func (d *Dashboard) addTick(token attackToken, attackers int, m *Metrics, samples []AttackResult) {
+	t := DashboardTick{
+		Tick:            token.Tick,
+		Step:            token.Step,
+		TargetRPS:       int(m.TargetRate),
+		Attackers:       attackers,
+		Rate:            m.Rate,
+		Requests:        m.Requests,
+		Success:         m.Success,
+		P50:             durationMs(m.Latencies.P50),
+		P95:             durationMs(m.Latencies.P95),
+		P99:             durationMs(m.Latencies.P99),
+		StatusCodes:     make(map[string]int),
+		ErrorCategories: make(map[string]int),
+		LabelP99:        make(map[string]float64),
+	}
+	for code, n := range m.StatusCodes {
+		t.StatusCodes[code] = n
+	}
+	labels := make(map[string]*Metrics)
+	for _, s := range samples {
+		if c := errorCategory(s); c != "" {
+			t.ErrorCategories[c]++
+		}
+		label := s.DoResult.RequestLabel
+		if _, ok := labels[label]; !ok {
+			labels[label] = NewMetrics()
+		}
+		labels[label].add(s)
+		d.Heatmap.Add(s)
+		ms := durationMs(s.Elapsed)
+		d.Histogram[sort.SearchFloat64s(latencyHistogramBuckets, ms)]++
+	}
+	for label, lm := range labels {
+		lm.update()
+		t.LabelP99[label] = durationMs(lm.Latencies.P99)
+	}
+	d.Ticks = append(d.Ticks, t)
+}
+
+// addSummary adds a row to summary table
+func (d *Dashboard) addSummary(name, value string) {
+	d.summary = append(d.summary, [2]string{name, value})
+}
+
+// WriteHTML renders dashboard to file
+func (d *Dashboard) WriteHTML(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.Render(f)
+}
+
+type dashboardLabelRow struct {
+	Label    string
+	Requests uint64
+	Success  string
+	P50      string
+	P95      string
+	P99      string
+	Max      string
+}
+
+type dashboardPage struct {
+	Name    string
+	Summary [][2]string
+	Charts  []template.HTML
+	Labels  []dashboardLabelRow
+}
+
+// Render renders self-contained html dashboard, charts are inlined as svg
+func (d *Dashboard) Render(w io.Writer) error {
+	page := dashboardPage{Name: d.Name}
+	page.Summary = append(page.Summary, [][2]string{
+		{"Started", d.start.Format(time.RFC3339)},
+		{"Ticks", strconv.Itoa(len(d.Ticks))},
+		{"Requests", strconv.FormatUint(d.Run.Requests, 10)},
+		{"Success", fmt.Sprintf("%.4f", d.Run.Success)},
+		{"Max rps", fmt.Sprintf("%.2f", d.maxRate())},
+		{"P50", d.Run.Latencies.P50.String()},
+		{"P95", d.Run.Latencies.P95.String()},
+		{"P99", d.Run.Latencies.P99.String()},
+		{"Max", d.Run.Latencies.Max.String()},
+		{"Status codes", formatCounts(d.Run.StatusCodes)},
+	}...)
+	page.Summary = append(page.Summary, d.summary...)
+
+	ticks := make([]float64, len(d.Ticks))
+	for i, t := range d.Ticks {
+		ticks[i] = float64(t.Tick)
+	}
+	page.Charts = []template.HTML{
+		d.lineChart("Latency percentiles", "ms", ticks, []string{"p50", "p95", "p99"}, func(t DashboardTick) []float64 {
+			return []float64{t.P50, t.P95, t.P99}
+		}),
+		d.lineChart("Achieved vs target rps", "rps", ticks, []string{"achieved", "target"}, func(t DashboardTick) []float64 {
+			return []float64{t.Rate, float64(t.TargetRPS)}
+		}),
+	}
+	codes := d.tickKeys(func(t DashboardTick) map[string]int { return t.StatusCodes })
+	if len(codes) > 0 {
+		page.Charts = append(page.Charts, d.lineChart("Status codes", "responses", ticks, codes, func(t DashboardTick) []float64 {
+			return countsOf(t.StatusCodes, codes)
+		}))
+	}
+	categories := d.tickKeys(func(t DashboardTick) map[string]int { return t.ErrorCategories })
+	if len(categories) > 0 {
+		page.Charts = append(page.Charts, d.lineChart("Errors", "requests", ticks, categories, func(t DashboardTick) []float64 {
+			return countsOf(t.ErrorCategories, categories)
+		}))
+	}
+	page.Charts = append(page.Charts,
+		d.lineChart("Attackers", "attackers", ticks, []string{"attackers"}, func(t DashboardTick) []float64 {
+			return []float64{float64(t.Attackers)}
+		}),
+		d.histogramChart(),
+		heatmapSVG(d.Heatmap, "Latency heatmap"),
+	)
+	labels := make([]string, 0, len(d.Labels))
+	for l := range d.Labels {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	if len(labels) > 1 {
+		page.Charts = append(page.Charts, d.lineChart("P99 by label", "ms", ticks, labels, func(t DashboardTick) []float64 {
+			res := make([]float64, len(labels))
+			for i, l := range labels {
+				res[i] = t.LabelP99[l]
+			}
+			return res
+		}))
+	}
+	for _, l := range labels {
+		m := d.Labels[l]
+		m.update()
+		page.Labels = append(page.Labels, dashboardLabelRow{
+			Label:    l,
+			Requests: m.Requests,
+			Success:  fmt.Sprintf("%.4f", m.Success),
+			P50:      m.Latencies.P50.String(),
+			P95:      m.Latencies.P95.String(),
+			P99:      m.Latencies.P99.String(),
+			Max:      m.Latencies.Max.String(),
+		})
+	}
+	return dashboardTemplate.Execute(w, page)
+}
+
+func (d *Dashboard) maxRate() float64 {
+	var max float64
+	for _, t := range d.Ticks {
+		max = math.Max(max, t.Rate)
+	}
+	return max
+}
+
+// tickKeys sorted union of keys of per tick counts
+func (d *Dashboard) tickKeys(counts func(t DashboardTick) map[string]int) []string {
+	set := make(map[string]int)
+	for _, t := range d.Ticks {
+		for k, n := range counts(t) {
+			set[k] += n
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stepBoundaries ticks at which a new step started
+func (d *Dashboard) stepBoundaries() []chart.GridLine {
+	res := make([]chart.GridLine, 0)
+	for i := 1; i < len(d.Ticks); i++ {
+		if d.Ticks[i].Step != d.Ticks[i-1].Step {
+			res = append(res, chart.GridLine{Value: float64(d.Ticks[i].Tick)})
+		}
+	}
+	return res
+}
+
+func (d *Dashboard) lineChart(title, yName string, x []float64, names []string, values func(t DashboardTick) []float64) template.HTML {
+	if len(x) == 0 {
+		return chartError(title, "no ticks reported")
+	}
+	ys := make([][]float64, len(names))
+	var max float64
+	for _, t := range d.Ticks {
+		for i, v := range values(t) {
+			ys[i] = append(ys[i], v)
+			max = math.Max(max, v)
+		}
+	}
+	series := make([]chart.Series, 0, len(names))
+	for i, n := range names {
+		series = append(series, chart.ContinuousSeries{
+			Name: n,
+			Style: chart.Style{
+				StrokeColor: chart.GetDefaultColor(i).WithAlpha(255),
+				StrokeWidth: 2,
+			},
+			XValues: x,
+			YValues: ys[i],
+		})
+	}
+	c := chart.Chart{
+		Title:  title,
+		Width:  1000,
+		Height: 350,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{
+			Name:           "Time (sec)",
+			Range:          &chart.ContinuousRange{Min: x[0], Max: math.Max(x[len(x)-1], x[0]+1)},
+			GridLines:      d.stepBoundaries(),
+			GridMajorStyle: chart.Style{StrokeColor: drawing.ColorFromHex("999999"), StrokeWidth: 1, StrokeDashArray: []float64{5, 5}},
+		},
+		YAxis: chart.YAxis{
+			Name:  yName,
+			Range: &chart.ContinuousRange{Min: 0, Max: math.Max(max*1.1, 1)},
+		},
+		Series: series,
+	}
+	c.Elements = []chart.Renderable{chart.LegendLeft(&c)}
+	return renderSVG(title, c.Render)
+}
+
+func (d *Dashboard) histogramChart() template.HTML {
+	title := "Latency distribution"
+	bars := make([]chart.Value, 0, len(d.Histogram))
+	var total uint64
+	for i, n := range d.Histogram {
+		label := ">" + formatFloat(latencyHistogramBuckets[len(latencyHistogramBuckets)-1])
+		if i < len(latencyHistogramBuckets) {
+			label = "<=" + formatFloat(latencyHistogramBuckets[i])
+		}
+		bars = append(bars, chart.Value{Label: label + "ms", Value: float64(n)})
+		total += n
+	}
+	if total == 0 {
+		return chartError(title, "no results")
+	}
+	c := chart.BarChart{
+		Title:    title,
+		Width:    1000,
+		Height:   350,
+		BarWidth: 50,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40},
+		},
+		XAxis: chart.Shown(),
+		YAxis: chart.YAxis{
+			Style: chart.Shown(),
+		},
+		Bars: bars,
+	}
+	return renderSVG(title, c.Render)
+}
+
+func renderSVG(title string, render func(rp chart.RendererProvider, w io.Writer) error) template.HTML {
+	var buf bytes.Buffer
+	if err := render(chart.SVG, &buf); err != nil {
+		return chartError(title, err.Error())
+	}
+	// svg is generated from numbers and escaped labels
+	return template.HTML(buf.String())
+}
+
+func chartError(title, msg string) template.HTML {
+	return template.HTML(fmt.Sprintf("<p>%s: %s</p>", template.HTMLEscapeString(title), template.HTMLEscapeString(msg)))
+}
+
+func countsOf(counts map[string]int, keys []string) []float64 {
+	res := make([]float64, len(keys))
+	for i, k := range keys {
+		res[i] = float64(counts[k])
+	}
+	return res
+}
+
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([]string, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, fmt.Sprintf("%s: %d", k, counts[k]))
+	}
+	if len(res) == 0 {
+		return "-"
+	}
+	return strings.Join(res, ", ")
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+table { border-collapse: collapse; margin-bottom: 20px; }
+td, th { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+.chart { margin-bottom: 20px; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<h2>Summary</h2>
+<table>
+{{range .Summary}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+{{range .Charts}}<div class="chart">{{.}}</div>
+{{end}}
+<h2>Labels</h2>
+<table>
+<tr><th>Label</th><th>Requests</th><th>Success</th><th>P50</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{range .Labels}}<tr><td>{{.Label}}</td><td>{{.Requests}}</td><td>{{.Success}}</td><td>{{.P50}}</td><td>{{.P95}}</td><td>{{.P99}}</td><td>{{.Max}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
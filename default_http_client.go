@@ -12,7 +12,8 @@ import (
 	jsoniter "github.com/json-iterator/go"
 )
 
-// NewLoggintHTTPClient creates new client with debug http
+// NewLoggintHTTPClient creates new client with debug http,
+// http phases are traced for requests made with attack context passed to Do
 func NewLoggingHTTPClient(debug bool, transportTimeout int) *http.Client {
 	var transport http.RoundTripper
 	http.DefaultTransport.(*http.Transport).MaxConnsPerHost = 65535
@@ -29,7 +30,7 @@ func NewLoggingHTTPClient(debug bool, transportTimeout int) *http.Client {
 	}
 	cookieJar, _ := cookiejar.New(nil)
 	return &http.Client{
-		Transport: transport,
+		Transport: &TracingTransport{transport},
 		Timeout:   time.Duration(transportTimeout) * time.Second,
 		Jar:       cookieJar,
 	}
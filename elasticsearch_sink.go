@@ -0,0 +1,264 @@
+package loaderbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// esDocument raw attack result document, latencies are in ms
+type esDocument struct {
+	Timestamp     time.Time      `json:"@timestamp"`
+	RunID         string         `json:"run_id"`
+	Runner        string         `json:"runner"`
+	Node          string         `json:"node,omitempty"`
+	Label         string         `json:"label"`
+	Step          int            `json:"step"`
+	Tick          int            `json:"tick"`
+	TargetRPS     int            `json:"target_rps"`
+	End           time.Time      `json:"end"`
+	ElapsedMs     float64        `json:"elapsed_ms"`
+	StatusCode    int            `json:"status_code"`
+	Success       bool           `json:"success"`
+	Error         string         `json:"error,omitempty"`
+	ErrorCategory string         `json:"error_category,omitempty"`
+	HTTP          *esHTTPTimings `json:"http,omitempty"`
+}
+
+type esHTTPTimings struct {
+	Requests    int     `json:"requests"`
+	ReusedConns int     `json:"reused_conns"`
+	DNSMs       float64 `json:"dns_ms"`
+	ConnectMs   float64 `json:"connect_ms"`
+	TLSMs       float64 `json:"tls_ms"`
+	TTFBMs      float64 `json:"ttfb_ms"`
+	TotalMs     float64 `json:"total_ms"`
+}
+
+type esResult struct {
+	node string
+	res  AttackResult
+}
+
+// esBulkResponse part of bulk api response needed to count rejected documents
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// ElasticsearchSink ships raw attack results to elasticsearch bulk api,
+// results are queued without blocking and sent in batches by background workers,
+// results are dropped and counted if queue is full
+type ElasticsearchSink struct {
+	cfg     *Elasticsearch
+	runID   string
+	runner  string
+	client  *http.Client
+	queue   chan esResult
+	wg      *sync.WaitGroup
+	dropped int64
+	failed  int64
+	L       *Logger
+}
+
+// NewElasticsearchSink starts workers, every document has runID and runner name
+func NewElasticsearchSink(cfg *Elasticsearch, runID string, runner string, l *Logger) *ElasticsearchSink {
+	s := &ElasticsearchSink{
+		cfg:    cfg,
+		runID:  runID,
+		runner: runner,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second},
+		queue:  make(chan esResult, cfg.QueueSize),
+		wg:     &sync.WaitGroup{},
+		L:      l,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Add queues result of a node, AggregatedNode for a single runner
+func (s *ElasticsearchSink) Add(node string, res AttackResult) {
+	select {
+	case s.queue <- esResult{node: node, res: res}:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Close sends queued results and waits for workers
+func (s *ElasticsearchSink) Close() error {
+	close(s.queue)
+	s.wg.Wait()
+	dropped, failed := atomic.LoadInt64(&s.dropped), atomic.LoadInt64(&s.failed)
+	if dropped > 0 || failed > 0 {
+		return fmt.Errorf("elasticsearch: %d results dropped as queue was full, %d failed to export", dropped, failed)
+	}
+	return nil
+}
+
+func (s *ElasticsearchSink) worker() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Duration(s.cfg.FlushIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+	batch := make([]esResult, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case r, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) >= s.cfg.BatchSize {
+				s.flush(batch)
+				batch = make([]esResult, 0, s.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]esResult, 0, s.cfg.BatchSize)
+			}
+		}
+	}
+}
+
+func (s *ElasticsearchSink) flush(batch []esResult) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := s.bulkBody(batch)
+	if err != nil {
+		atomic.AddInt64(&s.failed, int64(len(batch)))
+		s.L.Errorf("elasticsearch: failed to encode batch: %s", err)
+		return
+	}
+	var rejected int
+	for attempt := 0; attempt <= s.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+		var retry bool
+		rejected, retry, err = s.bulk(body)
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		atomic.AddInt64(&s.failed, int64(len(batch)))
+		s.L.Errorf("elasticsearch: failed to export %d results: %s", len(batch), err)
+		return
+	}
+	atomic.AddInt64(&s.failed, int64(rejected))
+}
+
+// bulkBody ndjson index actions
+func (s *ElasticsearchSink) bulkBody(batch []esResult) ([]byte, error) {
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": s.cfg.Index}})
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, r := range batch {
+		doc, err := json.Marshal(newESDocument(s.runID, s.runner, r.node, r.res))
+		if err != nil {
+			return nil, err
+		}
+		b.Write(action)
+		b.WriteByte('\n')
+		b.Write(doc)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// bulk sends one bulk request, returns amount of rejected documents and if failed request can be retried
+func (s *ElasticsearchSink) bulk(body []byte) (int, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(s.cfg.URL, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case s.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.cfg.APIKey)
+	case s.cfg.Username != "":
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+		return 0, retry, fmt.Errorf("elasticsearch responded %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var br esBulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
+		return 0, false, fmt.Errorf("failed to decode bulk response: %s", err)
+	}
+	if !br.Errors {
+		return 0, false, nil
+	}
+	var rejected int
+	for _, item := range br.Items {
+		for _, r := range item {
+			if r.Status/100 == 2 {
+				continue
+			}
+			if rejected == 0 {
+				s.L.Warnf("elasticsearch: document rejected: %s: %s", r.Error.Type, r.Error.Reason)
+			}
+			rejected++
+		}
+	}
+	return rejected, false, nil
+}
+
+func newESDocument(runID string, runner string, node string, res AttackResult) esDocument {
+	doc := esDocument{
+		Timestamp:     res.Begin,
+		RunID:         runID,
+		Runner:        runner,
+		Node:          node,
+		Label:         res.DoResult.RequestLabel,
+		Step:          res.AttackToken.Step,
+		Tick:          res.AttackToken.Tick,
+		TargetRPS:     res.AttackToken.TargetRPS,
+		End:           res.End,
+		ElapsedMs:     durationMs(res.Elapsed),
+		StatusCode:    res.DoResult.StatusCode,
+		Success:       res.DoResult.Error == "",
+		Error:         res.DoResult.Error,
+		ErrorCategory: errorCategory(res),
+	}
+	if t := res.HTTPTimings; t != nil {
+		doc.HTTP = &esHTTPTimings{
+			Requests:    t.Requests,
+			ReusedConns: t.ReusedConns,
+			DNSMs:       durationMs(t.DNS),
+			ConnectMs:   durationMs(t.Connect),
+			TLSMs:       durationMs(t.TLS),
+			TTFBMs:      durationMs(t.TTFB),
+			TotalMs:     durationMs(t.Total),
+		}
+	}
+	return doc
+}
@@ -0,0 +1,131 @@
+package loaderbot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// esStandIn bulk api stand-in, responds with status of every call in order, then with 200
+type esStandIn struct {
+	mu       *sync.Mutex
+	statuses []int
+	bulks    int
+	docs     []esDocument
+	release  chan struct{}
+}
+
+func newESStandIn(statuses ...int) *esStandIn {
+	return &esStandIn{mu: &sync.Mutex{}, statuses: statuses}
+}
+
+func (s *esStandIn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.release != nil {
+		<-s.release
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bulks++
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for i := 0; sc.Scan(); i++ {
+		if i%2 == 0 {
+			continue
+		}
+		var doc esDocument
+		_ = json.Unmarshal(sc.Bytes(), &doc)
+		s.docs = append(s.docs, doc)
+	}
+	_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+}
+
+func TestCommonElasticsearchExport(t *testing.T) {
+	es := newESStandIn(http.StatusServiceUnavailable)
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+	r := NewRunner(&RunnerConfig{
+		Name:            "es_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		Elasticsearch: &Elasticsearch{
+			URL:             srv.URL,
+			BatchSize:       5,
+			FlushIntervalMs: 100,
+			Retries:         1,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	require.NotEmpty(t, r.es.runID)
+	// first bulk is retried after 503
+	require.Greater(t, es.bulks, len(es.docs)/5)
+	require.InDelta(t, 30, len(es.docs), 2)
+	for _, d := range es.docs {
+		require.Equal(t, r.es.runID, d.RunID)
+		require.Equal(t, "es_runner", d.Runner)
+		require.Equal(t, 1, d.Step)
+		require.True(t, d.Success)
+		require.False(t, d.Timestamp.IsZero())
+		require.Empty(t, d.Node)
+	}
+}
+
+func TestCommonElasticsearchBackpressure(t *testing.T) {
+	es := newESStandIn(http.StatusBadRequest)
+	es.release = make(chan struct{})
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+	cfg := &Elasticsearch{
+		URL:             srv.URL,
+		Index:           "results",
+		BatchSize:       2,
+		FlushIntervalMs: 1000,
+		QueueSize:       2,
+		Workers:         1,
+		TimeoutSec:      10,
+		Retries:         3,
+	}
+	rcfg := &RunnerConfig{Name: "es_runner"}
+	rcfg.DefaultCfgValues()
+	s := NewElasticsearchSink(cfg, "run", "es_runner", NewLogger(rcfg))
+	start := time.Now()
+	// worker is blocked on the first batch, queue fits two more results, the rest are dropped
+	for i := 0; i < 10; i++ {
+		s.Add(AggregatedNode, AttackResult{DoResult: DoResult{RequestLabel: "get"}})
+		if i == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	close(es.release)
+	// 400 is not retried
+	require.EqualError(t, s.Close(), "elasticsearch: 6 results dropped as queue was full, 2 failed to export")
+	require.Equal(t, 2, es.bulks)
+	require.Len(t, es.docs, 2)
+}
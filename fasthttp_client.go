@@ -1,6 +1,7 @@
 package loaderbot
 
 import (
+	"context"
 	"log"
 	"reflect"
 	"time"
@@ -43,6 +44,26 @@ func (m *FastHTTPClient) Do(req *fasthttp.Request, resp *fasthttp.Response) erro
 	return nil
 }
 
+// DoCtx performs request and records its timing for attack context passed to Do,
+// fasthttp has no tracing hooks, so only total round trip time is recorded,
+// traceparent header is set if attack is sampled
+func (m *FastHTTPClient) DoCtx(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if sc, ok := SpanContextFromCtx(ctx); ok {
+		req.Header.Set(TraceparentHeader, sc.Traceparent())
+	}
+	collector := httpTraceCollectorFromCtx(ctx)
+	if collector == nil {
+		return m.Do(req, resp)
+	}
+	start := time.Now()
+	err := m.Do(req, resp)
+	collector.add(HTTPTimings{
+		Requests: 1,
+		Total:    time.Since(start),
+	})
+	return err
+}
+
 func UnmarshalAnyJson(d []byte, typ interface{}) (interface{}, error) {
 	if typ == nil || d == nil {
 		return nil, nil
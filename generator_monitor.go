@@ -0,0 +1,158 @@
+package loaderbot
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// GeneratorStats load generator process health sample, if generator is saturated results are not trustworthy
+type GeneratorStats struct {
+	// Second since the test start
+	Second int
+	// CPUPercent process cpu usage over sample interval normalized by all cores, [0, 100]
+	CPUPercent float64
+	// HeapAlloc bytes of allocated heap objects
+	HeapAlloc uint64
+	// Sys total bytes of memory obtained from the OS
+	Sys uint64
+	// NumGC amount of gc cycles during sample interval
+	NumGC uint32
+	// GCPauseMax max gc pause during sample interval
+	GCPauseMax time.Duration
+	// Goroutines amount of goroutines
+	Goroutines int
+	// OpenFDs amount of open file descriptors, -1 if unknown for platform
+	OpenFDs int
+	// MaxFDs open file descriptors limit, 0 if unknown for platform
+	MaxFDs uint64
+	// ResultsQueue amount of results waiting to be collected
+	ResultsQueue int
+	// ResultsQueueCap results queue capacity
+	ResultsQueueCap int
+	// Warnings describe which limits were reached, empty if generator is healthy
+	Warnings []string
+}
+
+// Saturated true if any generator limit was reached in sample
+func (s GeneratorStats) Saturated() bool {
+	return len(s.Warnings) > 0
+}
+
+// generatorSampler samples process stats, keeps previous sample to compute interval deltas
+type generatorSampler struct {
+	limits    *GeneratorLimits
+	start     time.Time
+	lastTime  time.Time
+	lastCPU   time.Duration
+	lastNumGC uint32
+}
+
+func newGeneratorSampler(limits *GeneratorLimits) *generatorSampler {
+	now := time.Now()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return &generatorSampler{
+		limits:    limits,
+		start:     now,
+		lastTime:  now,
+		lastCPU:   processCPUTime(),
+		lastNumGC: ms.NumGC,
+	}
+}
+
+func (g *generatorSampler) sample(resultsQueue, resultsQueueCap int) GeneratorStats {
+	now := time.Now()
+	cpu := processCPUTime()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	s := GeneratorStats{
+		Second:          int(now.Sub(g.start).Seconds()),
+		HeapAlloc:       ms.HeapAlloc,
+		Sys:             ms.Sys,
+		NumGC:           ms.NumGC - g.lastNumGC,
+		Goroutines:      runtime.NumGoroutine(),
+		OpenFDs:         openFDs(),
+		MaxFDs:          maxFDs(),
+		ResultsQueue:    resultsQueue,
+		ResultsQueueCap: resultsQueueCap,
+	}
+	if wall := now.Sub(g.lastTime); wall > 0 {
+		s.CPUPercent = float64(cpu-g.lastCPU) / float64(wall) / float64(runtime.NumCPU()) * 100
+	}
+	// PauseNs is a circular buffer of recent gc pauses
+	for i := g.lastNumGC + 1; i <= ms.NumGC && ms.NumGC-i < uint32(len(ms.PauseNs)); i++ {
+		if p := time.Duration(ms.PauseNs[(i+255)%256]); p > s.GCPauseMax {
+			s.GCPauseMax = p
+		}
+	}
+	g.lastTime = now
+	g.lastCPU = cpu
+	g.lastNumGC = ms.NumGC
+	s.Warnings = g.check(s)
+	return s
+}
+
+// check compares sample with limits
+func (g *generatorSampler) check(s GeneratorStats) []string {
+	var warnings []string
+	if s.CPUPercent >= g.limits.CPUPercent {
+		warnings = append(warnings, fmt.Sprintf("cpu usage %.2f%% >= %.2f%%", s.CPUPercent, g.limits.CPUPercent))
+	}
+	if s.ResultsQueueCap > 0 && float64(s.ResultsQueue)/float64(s.ResultsQueueCap) >= g.limits.QueueFillRatio {
+		warnings = append(warnings, fmt.Sprintf("results queue %d/%d is almost full", s.ResultsQueue, s.ResultsQueueCap))
+	}
+	if s.OpenFDs > 0 && s.MaxFDs > 0 && float64(s.OpenFDs)/float64(s.MaxFDs) >= g.limits.FDFillRatio {
+		warnings = append(warnings, fmt.Sprintf("open fds %d/%d are close to limit", s.OpenFDs, s.MaxFDs))
+	}
+	if s.GCPauseMax >= time.Duration(g.limits.GCPauseMs)*time.Millisecond {
+		warnings = append(warnings, fmt.Sprintf("gc pause %v >= %dms", s.GCPauseMax, g.limits.GCPauseMs))
+	}
+	return warnings
+}
+
+// monitorGenerator samples load generator process every second until test ends
+func (r *Runner) monitorGenerator() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		sampler := newGeneratorSampler(r.Cfg.GeneratorLimits)
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.TimeoutCtx.Done():
+				return
+			case <-ticker.C:
+				s := sampler.sample(len(r.results), cap(r.results))
+				r.L.Debugf(
+					GeneratorTemplate,
+					s.CPUPercent,
+					s.HeapAlloc/1024/1024,
+					s.GCPauseMax,
+					s.Goroutines,
+					s.OpenFDs,
+					s.ResultsQueue,
+				)
+				if s.Saturated() {
+					atomic.AddInt64(&r.SaturatedSamples, 1)
+					r.L.Warnf("load generator is saturated, results may be wrong: %s", strings.Join(s.Warnings, ", "))
+				}
+				if r.Cfg.ReportOptions.CSV {
+					r.Report.writeGeneratorEntry(s)
+				}
+				if r.PromReporter != nil {
+					r.PromReporter.reportGenerator(s)
+				}
+			}
+		}
+	}()
+}
+
+// Trustworthy false if load generator was saturated during the test
+func (r *Runner) Trustworthy() bool {
+	return atomic.LoadInt64(&r.SaturatedSamples) == 0
+}
@@ -0,0 +1,24 @@
+package loaderbot
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonGeneratorSaturation(t *testing.T) {
+	cfg := DefaultRunnerCfg()
+	// samples are taken without interval, cpu usage is not representative
+	cfg.GeneratorLimits = &GeneratorLimits{CPUPercent: math.MaxFloat64}
+	cfg.DefaultCfgValues()
+	s := newGeneratorSampler(cfg.GeneratorLimits)
+	healthy := s.sample(10, 100)
+	require.False(t, healthy.Saturated(), healthy.Warnings)
+	require.Greater(t, healthy.Goroutines, 0)
+
+	saturated := s.sample(90, 100)
+	require.True(t, saturated.Saturated())
+	require.Len(t, saturated.Warnings, 1)
+	require.Contains(t, saturated.Warnings[0], "results queue")
+}
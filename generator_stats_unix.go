@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package loaderbot
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// processCPUTime user and system cpu time consumed by the process
+func processCPUTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// openFDs amount of open file descriptors, -1 if it can't be determined
+func openFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		f, err := os.Open(dir)
+		if err != nil {
+			continue
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err == nil {
+			// opened dir descriptor is listed too
+			return len(names) - 1
+		}
+	}
+	return -1
+}
+
+// maxFDs soft limit of open file descriptors
+func maxFDs() uint64 {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0
+	}
+	return uint64(rl.Cur)
+}
@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package loaderbot
+
+import (
+	"time"
+)
+
+// processCPUTime is not supported on windows
+func processCPUTime() time.Duration {
+	return 0
+}
+
+// openFDs is not supported on windows
+func openFDs() int {
+	return -1
+}
+
+// maxFDs is not supported on windows
+func maxFDs() uint64 {
+	return 0
+}
@@ -3,6 +3,7 @@ package loaderbot
 import (
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -79,7 +80,8 @@ func parsePercsData(path string) (map[string]*ChartLine, error) {
 		if err != nil {
 			return nil, err
 		}
-		if len(record) != 6 {
+		// step, target rps and success columns are optional
+		if len(record) < 6 {
 			return nil, errors.New("malformed csv")
 		}
 
@@ -130,6 +132,11 @@ func parsePercsData(path string) (map[string]*ChartLine, error) {
 }
 
 func PercsChart(path string, title string) (*charts.Line, error) {
+	return PercsChartWithKnee(path, title, nil)
+}
+
+// PercsChartWithKnee creates rps and percentiles chart, knee steps are marked if knee is not nil
+func PercsChartWithKnee(path string, title string, knee *Knee) (*charts.Line, error) {
 	d, err := parsePercsData(path)
 	if err != nil {
 		return nil, err
@@ -143,7 +150,218 @@ func PercsChart(path string, title string) (*charts.Line, error) {
 	)
 	line.AddXAxis(d["rps"].XValues)
 	for k, v := range d {
-		line.AddYAxis(k, v.YValues, defaultMaxLabel(k)...)
+		opts := defaultMaxLabel(k)
+		if k == "rps" {
+			opts = append(opts, kneeMarkLines(knee)...)
+		}
+		line.AddYAxis(k, v.YValues, opts...)
+	}
+	return line, nil
+}
+
+func parseCustomMetricsData(path string) (map[string]map[string]*ChartLine, []string, error) {
+	reader := openCSV(path)
+	// skip csv header
+	_, _ = reader.Read()
+
+	metrics := make(map[string]map[string]*ChartLine)
+	names := make([]string, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(record) != len(CustomMetricsCsvHeader) {
+			return nil, nil, errors.New("malformed csv")
+		}
+		tick, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := record[2]
+		if _, ok := metrics[name]; !ok {
+			metrics[name] = make(map[string]*ChartLine)
+			names = append(names, name)
+		}
+		// counters are charted by sum, gauges by mean and max, timings by percentiles
+		var columns map[string]int
+		switch record[3] {
+		case CustomCounter.String():
+			columns = map[string]int{"sum": 5}
+		case CustomGauge.String():
+			columns = map[string]int{"mean": 8, "max": 7}
+		case CustomTiming.String():
+			columns = map[string]int{"p50": 9, "p95": 10, "p99": 11}
+		default:
+			return nil, nil, errors.New("unknown custom metric type")
+		}
+		for lineName, col := range columns {
+			if _, ok := metrics[name][lineName]; !ok {
+				metrics[name][lineName] = &ChartLine{}
+			}
+			v, err := strconv.ParseFloat(record[col], 64)
+			if err != nil {
+				return nil, nil, err
+			}
+			metrics[name][lineName].XValues = append(metrics[name][lineName].XValues, tick)
+			metrics[name][lineName].YValues = append(metrics[name][lineName].YValues, v)
+		}
+	}
+	return metrics, names, nil
+}
+
+// CustomMetricsCharts creates one chart per custom metric, returns no charts if no custom metrics were emitted
+func CustomMetricsCharts(path string) ([]*charts.Line, error) {
+	d, names, err := parseCustomMetricsData(path)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*charts.Line, 0)
+	for _, name := range names {
+		line := charts.NewLine()
+		line.SetGlobalOptions(
+			charts.DataZoomOpts{},
+			charts.TitleOpts{Title: name},
+			charts.XAxisOpts{Name: "Time (sec)"},
+		)
+		var xAxisSet bool
+		for k, v := range d[name] {
+			if !xAxisSet {
+				line.AddXAxis(v.XValues)
+				xAxisSet = true
+			}
+			line.AddYAxis(k, v.YValues, defaultMaxLabel(k)...)
+		}
+		res = append(res, line)
+	}
+	return res, nil
+}
+
+// InFlightChart creates in-flight attacks and idle attackers chart, nil if no ticks were sampled
+func InFlightChart(path string) (*charts.Line, error) {
+	reader := openCSV(path)
+	// skip csv header
+	_, _ = reader.Read()
+
+	names := []string{"in flight min", "in flight mean", "in flight max", "idle mean"}
+	columns := []int{1, 2, 3, 5}
+	lines := make(map[string]*ChartLine)
+	for _, n := range names {
+		lines[n] = &ChartLine{}
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(InFlightCsvHeader) {
+			return nil, errors.New("malformed csv")
+		}
+		tick, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range names {
+			v, err := strconv.ParseFloat(record[columns[i]], 64)
+			if err != nil {
+				return nil, err
+			}
+			lines[n].XValues = append(lines[n].XValues, tick)
+			lines[n].YValues = append(lines[n].YValues, v)
+		}
+	}
+	if len(lines[names[0]].XValues) == 0 {
+		return nil, nil
+	}
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.DataZoomOpts{},
+		charts.TitleOpts{Title: "In flight"},
+		charts.XAxisOpts{Name: "Time (sec)"},
+		charts.YAxisOpts{Name: "Attacks"},
+	)
+	line.AddXAxis(lines[names[0]].XValues)
+	for _, n := range names {
+		line.AddYAxis(n, lines[n].YValues, defaultMaxLabel(n)...)
+	}
+	return line, nil
+}
+
+// SLOChart creates Apdex and objectives compliance chart of ticks, nil if no SLO is set
+func SLOChart(path string) (*charts.Line, error) {
+	reader := openCSV(path)
+	// skip csv header
+	_, _ = reader.Read()
+
+	ticks := make([]float64, 0)
+	apdex := &ChartLine{}
+	var apdexSet bool
+	lines := make(map[string]*ChartLine)
+	names := make([]string, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(SLOCsvHeader) {
+			return nil, errors.New("malformed csv")
+		}
+		if record[0] != "tick" {
+			continue
+		}
+		tick, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		// objectives of a tick are written in consecutive rows
+		if len(ticks) == 0 || ticks[len(ticks)-1] != tick {
+			ticks = append(ticks, tick)
+			v, err := strconv.ParseFloat(record[4], 64)
+			if err != nil {
+				return nil, err
+			}
+			apdexSet = apdexSet || v > 0
+			apdex.YValues = append(apdex.YValues, v)
+		}
+		name := record[5]
+		if name == "" {
+			continue
+		}
+		if _, ok := lines[name]; !ok {
+			lines[name] = &ChartLine{}
+			names = append(names, name)
+		}
+		v, err := strconv.ParseFloat(record[8], 64)
+		if err != nil {
+			return nil, err
+		}
+		lines[name].YValues = append(lines[name].YValues, v)
+	}
+	if len(ticks) == 0 {
+		return nil, nil
+	}
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.DataZoomOpts{},
+		charts.TitleOpts{Title: "SLO"},
+		charts.XAxisOpts{Name: "Time (sec)"},
+		charts.YAxisOpts{Name: "Ratio"},
+	)
+	line.AddXAxis(ticks)
+	if apdexSet {
+		line.AddYAxis("apdex", apdex.YValues)
+	}
+	for _, n := range names {
+		line.AddYAxis(n, lines[n].YValues)
 	}
 	return line, nil
 }
@@ -170,12 +388,26 @@ func RenderEChart(data *charts.Line, name string) {
 	f, err := os.Create(name)
 	if err != nil {
 		log.Println(err)
+		return
 	}
+	defer f.Close()
 	if err := data.Render(f); err != nil {
 		log.Fatal(err)
 	}
 }
 
+func RenderEPage(page *charts.Page, name string) {
+	f, err := os.Create(name)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+	if err := page.Render(f); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // ReportScaling scaling chart, data must be written in csv in format:
 // ${handle_name},${network_nodes},${max_rps}
 func ReportScaling(inputCsv, outHtml string) {
@@ -187,6 +419,35 @@ func ReportScaling(inputCsv, outHtml string) {
 	// html2png(outHtml)
 }
 
+// kneeMarkLines marks start ticks of last healthy, saturation and failure steps
+func kneeMarkLines(knee *Knee) []charts.SeriesOptser {
+	if knee == nil {
+		return nil
+	}
+	res := make([]charts.SeriesOptser, 0)
+	marks := []struct {
+		name string
+		step *StepSummary
+	}{
+		{"last healthy", knee.LastHealthy},
+		{"saturation", knee.Saturation},
+		{"failure", knee.Failure},
+	}
+	for _, m := range marks {
+		if m.step == nil {
+			continue
+		}
+		res = append(res, charts.MLNameXAxisItem{
+			Name:  fmt.Sprintf("%s (%d rps)", m.name, m.step.TargetRPS),
+			XAxis: strconv.Itoa(m.step.StartTick),
+		})
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return append(res, charts.MLStyleOpts{Label: charts.LabelTextOpts{Show: true, Formatter: "{b}"}})
+}
+
 // draws max label for every line
 func defaultMaxLabel(metric string) []charts.SeriesOptser {
 	return []charts.SeriesOptser{
@@ -0,0 +1,223 @@
+package loaderbot
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+
+	"github.com/go-echarts/go-echarts/charts"
+	"github.com/wcharczuk/go-chart"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+const (
+	// HeatmapPNGFile latency heatmap png
+	HeatmapPNGFile = "heatmap_%s_%s_%d.png"
+)
+
+var (
+	heatmapLowColor  = drawing.Color{R: 247, G: 251, B: 255, A: 255}
+	heatmapHighColor = drawing.Color{R: 8, G: 48, B: 107, A: 255}
+)
+
+// LatencyHeatmap counts requests by second of completion and latency bucket,
+// unlike percentiles it shows multimodal latency, e.g. cache hits and misses
+type LatencyHeatmap struct {
+	// Buckets upper bounds of latency buckets, ms, the last bucket is unbounded
+	Buckets []float64
+
+	counts map[int64][]uint64
+}
+
+// NewLatencyHeatmap creates empty heatmap, default histogram buckets are used if none provided
+func NewLatencyHeatmap(buckets []float64) *LatencyHeatmap {
+	if len(buckets) == 0 {
+		buckets = latencyHistogramBuckets
+	}
+	return &LatencyHeatmap{
+		Buckets: buckets,
+		counts:  make(map[int64][]uint64),
+	}
+}
+
+// LoadLatencyHeatmap builds heatmap from raw results recorded in binary log or requests csv
+func LoadLatencyHeatmap(rawResultsPath string, buckets []float64) (*LatencyHeatmap, error) {
+	h := NewLatencyHeatmap(buckets)
+	err := ReadRawResults(rawResultsPath, func(res AttackResult) error {
+		h.Add(res)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Add counts result in bucket of its latency in second it completed
+func (h *LatencyHeatmap) Add(res AttackResult) {
+	sec := res.End.Unix()
+	if _, ok := h.counts[sec]; !ok {
+		h.counts[sec] = make([]uint64, len(h.Buckets)+1)
+	}
+	h.counts[sec][sort.SearchFloat64s(h.Buckets, durationMs(res.Elapsed))]++
+}
+
+// Rows counts by latency bucket of every second from the first to the last one with completed requests
+func (h *LatencyHeatmap) Rows() [][]uint64 {
+	if len(h.counts) == 0 {
+		return nil
+	}
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for sec := range h.counts {
+		if sec < first {
+			first = sec
+		}
+		if sec > last {
+			last = sec
+		}
+	}
+	rows := make([][]uint64, 0, last-first+1)
+	for sec := first; sec <= last; sec++ {
+		row, ok := h.counts[sec]
+		if !ok {
+			row = make([]uint64, len(h.Buckets)+1)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// BucketLabels latency bucket names
+func (h *LatencyHeatmap) BucketLabels() []string {
+	res := make([]string, 0, len(h.Buckets)+1)
+	for _, b := range h.Buckets {
+		res = append(res, "<="+formatFloat(b)+"ms")
+	}
+	return append(res, ">"+formatFloat(h.Buckets[len(h.Buckets)-1])+"ms")
+}
+
+func (h *LatencyHeatmap) maxCount(rows [][]uint64) uint64 {
+	var max uint64
+	for _, row := range rows {
+		for _, n := range row {
+			if n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
+// HeatmapChart creates latency heatmap chart, empty cells are not drawn
+func HeatmapChart(h *LatencyHeatmap, title string) (*charts.HeatMap, error) {
+	rows := h.Rows()
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty heatmap, nothing to plot")
+	}
+	seconds := make([]int, len(rows))
+	data := make([][3]interface{}, 0)
+	for sec, row := range rows {
+		seconds[sec] = sec
+		for bucket, n := range row {
+			if n > 0 {
+				data = append(data, [3]interface{}{sec, bucket, n})
+			}
+		}
+	}
+	hm := charts.NewHeatMap()
+	hm.SetGlobalOptions(
+		charts.TitleOpts{Title: title},
+		charts.XAxisOpts{Name: "Time (sec)", Type: "category", SplitArea: charts.SplitAreaOpts{Show: true}},
+		charts.YAxisOpts{Name: "Latency", Type: "category", Data: h.BucketLabels(), SplitArea: charts.SplitAreaOpts{Show: true}},
+		charts.VisualMapOpts{
+			Calculable: true,
+			Max:        float32(h.maxCount(rows)),
+			InRange:    charts.VMInRange{Color: []string{"#f7fbff", "#08306b"}},
+		},
+	)
+	hm.AddXAxis(seconds)
+	hm.AddYAxis("requests", data)
+	return hm, nil
+}
+
+// HeatmapPNGChart creates latency heatmap chart to render as png or svg,
+// cell color is log scaled by requests count so rare latencies are visible too
+func HeatmapPNGChart(h *LatencyHeatmap, title string) (*chart.Chart, error) {
+	rows := h.Rows()
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty heatmap, nothing to plot")
+	}
+	labels := h.BucketLabels()
+	yTicks := make([]chart.Tick, 0, len(labels)+1)
+	yTicks = append(yTicks, chart.Tick{Value: 0})
+	for i, l := range labels {
+		yTicks = append(yTicks, chart.Tick{Value: float64(i + 1), Label: l})
+	}
+	seconds := float64(len(rows))
+	max := math.Log1p(float64(h.maxCount(rows)))
+	c := &chart.Chart{
+		Title:  title,
+		Width:  1000,
+		Height: 400,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{
+			Name:  "Time (sec)",
+			Range: &chart.ContinuousRange{Min: 0, Max: seconds},
+		},
+		YAxis: chart.YAxis{
+			Name:  "Latency",
+			Range: &chart.ContinuousRange{Min: 0, Max: float64(len(labels))},
+			Ticks: yTicks,
+		},
+		// axes are drawn for series only, cells are drawn over it
+		Series: []chart.Series{chart.ContinuousSeries{
+			Style:   chart.Style{StrokeColor: drawing.ColorTransparent, StrokeWidth: 1},
+			XValues: []float64{0, seconds},
+			YValues: []float64{0, 0},
+		}},
+	}
+	c.Elements = []chart.Renderable{func(r chart.Renderer, canvas chart.Box, _ chart.Style) {
+		cellWidth := float64(canvas.Width()) / seconds
+		cellHeight := float64(canvas.Height()) / float64(len(labels))
+		for sec, row := range rows {
+			for bucket, n := range row {
+				if n == 0 {
+					continue
+				}
+				color := heatmapColor(math.Log1p(float64(n)) / max)
+				chart.Draw.Box(r, chart.Box{
+					Left:   canvas.Left + int(float64(sec)*cellWidth),
+					Right:  canvas.Left + int(math.Ceil(float64(sec+1)*cellWidth)),
+					Top:    canvas.Bottom - int(math.Ceil(float64(bucket+1)*cellHeight)),
+					Bottom: canvas.Bottom - int(float64(bucket)*cellHeight),
+				}, chart.Style{FillColor: color, StrokeColor: color, StrokeWidth: 1})
+			}
+		}
+	}}
+	return c, nil
+}
+
+// heatmapSVG renders heatmap to embed in html
+func heatmapSVG(h *LatencyHeatmap, title string) template.HTML {
+	c, err := HeatmapPNGChart(h, title)
+	if err != nil {
+		return chartError(title, err.Error())
+	}
+	return renderSVG(title, c.Render)
+}
+
+// heatmapColor interpolates between low and high colors, v in [0, 1]
+func heatmapColor(v float64) drawing.Color {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*v)
+	}
+	return drawing.Color{
+		R: mix(heatmapLowColor.R, heatmapHighColor.R),
+		G: mix(heatmapLowColor.G, heatmapHighColor.G),
+		B: mix(heatmapLowColor.B, heatmapHighColor.B),
+		A: 255,
+	}
+}
@@ -0,0 +1,260 @@
+package loaderbot
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/streadway/quantile"
+)
+
+type httpTraceCtxKey struct{}
+
+// HTTPTimings phase timings of http requests made inside one Attack.Do call,
+// if Do made several requests durations are summed
+type HTTPTimings struct {
+	// Requests amount of timed requests
+	Requests int
+	// Traced amount of requests with known phases, fasthttp requests have Total only
+	Traced int
+	// ReusedConns amount of requests made on reused connection
+	ReusedConns int
+	// DNS time spent in dns lookup
+	DNS time.Duration
+	// Connect time spent establishing tcp connection
+	Connect time.Duration
+	// TLS time spent in tls handshake
+	TLS time.Duration
+	// TTFB time from request written to the first response byte, server time
+	TTFB time.Duration
+	// Total time from round trip start to the first response byte
+	Total time.Duration
+}
+
+// httpTraceCollector collects timings of all requests made with attack context,
+// Do may still run after timeout, so access is guarded
+type httpTraceCollector struct {
+	mu      sync.Mutex
+	timings HTTPTimings
+}
+
+func (c *httpTraceCollector) add(t HTTPTimings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timings.Requests += t.Requests
+	c.timings.Traced += t.Traced
+	c.timings.ReusedConns += t.ReusedConns
+	c.timings.DNS += t.DNS
+	c.timings.Connect += t.Connect
+	c.timings.TLS += t.TLS
+	c.timings.TTFB += t.TTFB
+	c.timings.Total += t.Total
+}
+
+// result returns collected timings or nil if no requests were traced
+func (c *httpTraceCollector) result() *HTTPTimings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timings.Requests == 0 {
+		return nil
+	}
+	t := c.timings
+	return &t
+}
+
+func withHTTPTraceCollector(ctx context.Context, c *httpTraceCollector) context.Context {
+	return context.WithValue(ctx, httpTraceCtxKey{}, c)
+}
+
+func httpTraceCollectorFromCtx(ctx context.Context) *httpTraceCollector {
+	c, _ := ctx.Value(httpTraceCtxKey{}).(*httpTraceCollector)
+	return c
+}
+
+// TracingTransport measures http phases of requests made with attack context,
+// injects traceparent header into requests of sampled attacks
+type TracingTransport struct {
+	r http.RoundTripper
+}
+
+// CloseIdleConnections closes idle connections of wrapped transport, so http.Client.CloseIdleConnections works
+func (t *TracingTransport) CloseIdleConnections() {
+	if c, ok := t.r.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if sc, ok := SpanContextFromCtx(req.Context()); ok {
+		req = req.Clone(req.Context())
+		req.Header.Set(TraceparentHeader, sc.Traceparent())
+	}
+	collector := httpTraceCollectorFromCtx(req.Context())
+	if collector == nil {
+		return t.r.RoundTrip(req)
+	}
+	var (
+		mu                                                        sync.Mutex
+		dnsStart, connectStart, tlsStart, wroteRequest, firstByte time.Time
+		timings                                                   = HTTPTimings{Requests: 1, Traced: 1}
+		roundStart                                                = time.Now()
+	)
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			mu.Lock()
+			timings.DNS = time.Since(dnsStart)
+			mu.Unlock()
+		},
+		ConnectStart: func(_, _ string) {
+			mu.Lock()
+			connectStart = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			mu.Lock()
+			timings.Connect = time.Since(connectStart)
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			mu.Lock()
+			timings.TLS = time.Since(tlsStart)
+			mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				mu.Lock()
+				timings.ReusedConns = 1
+				mu.Unlock()
+			}
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			mu.Lock()
+			wroteRequest = time.Now()
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			firstByte = time.Now()
+			mu.Unlock()
+		},
+	}
+	resp, err := t.r.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	mu.Lock()
+	if firstByte.IsZero() {
+		firstByte = time.Now()
+	}
+	if !wroteRequest.IsZero() {
+		timings.TTFB = firstByte.Sub(wroteRequest)
+	}
+	timings.Total = firstByte.Sub(roundStart)
+	mu.Unlock()
+	collector.add(timings)
+	return resp, err
+}
+
+// HTTPPhaseMetrics holds computed http phases latency metrics
+type HTTPPhaseMetrics struct {
+	// Requests is the number of timed http requests.
+	Requests uint64 `json:"requests"`
+	// Traced is the number of requests with known phases, DNS, Connect, TLS and TTFB are computed from them only.
+	Traced uint64 `json:"traced"`
+	// ConnReuse is the ratio of requests made on reused connection.
+	ConnReuse float64          `json:"conn_reuse"`
+	DNS       PhasePercentiles `json:"dns"`
+	Connect   PhasePercentiles `json:"connect"`
+	TLS       PhasePercentiles `json:"tls"`
+	TTFB      PhasePercentiles `json:"ttfb"`
+	Total     PhasePercentiles `json:"total"`
+
+	reused     uint64
+	estimators map[string]*quantile.Estimator
+}
+
+// PhasePercentiles percentiles of one http phase
+type PhasePercentiles struct {
+	P50 time.Duration `json:"50th"`
+	P95 time.Duration `json:"95th"`
+	P99 time.Duration `json:"99th"`
+}
+
+var httpPhases = []string{"dns", "connect", "tls", "ttfb", "total"}
+
+func (m *HTTPPhaseMetrics) add(t *HTTPTimings) {
+	if t == nil {
+		return
+	}
+	if m.estimators == nil {
+		m.estimators = make(map[string]*quantile.Estimator)
+		for _, p := range httpPhases {
+			m.estimators[p] = quantile.New(
+				quantile.Known(0.50, 0.01),
+				quantile.Known(0.95, 0.001),
+				quantile.Known(0.99, 0.0005),
+			)
+		}
+	}
+	m.Requests += uint64(t.Requests)
+	m.reused += uint64(t.ReusedConns)
+	m.estimators["total"].Add(float64(t.Total))
+	// phases of partially traced attack are incomplete sums
+	if t.Traced == 0 || t.Traced != t.Requests {
+		return
+	}
+	m.Traced += uint64(t.Traced)
+	m.estimators["dns"].Add(float64(t.DNS))
+	m.estimators["connect"].Add(float64(t.Connect))
+	m.estimators["tls"].Add(float64(t.TLS))
+	m.estimators["ttfb"].Add(float64(t.TTFB))
+}
+
+func (m *HTTPPhaseMetrics) update() {
+	if m.Requests == 0 {
+		return
+	}
+	m.ConnReuse = float64(m.reused) / float64(m.Requests)
+	m.Total = phasePercentiles(m.estimators["total"])
+	if m.Traced == 0 {
+		return
+	}
+	m.DNS = phasePercentiles(m.estimators["dns"])
+	m.Connect = phasePercentiles(m.estimators["connect"])
+	m.TLS = phasePercentiles(m.estimators["tls"])
+	m.TTFB = phasePercentiles(m.estimators["ttfb"])
+}
+
+// byName returns phase percentiles by phase name
+func (m *HTTPPhaseMetrics) byName(phase string) PhasePercentiles {
+	switch phase {
+	case "dns":
+		return m.DNS
+	case "connect":
+		return m.Connect
+	case "tls":
+		return m.TLS
+	case "ttfb":
+		return m.TTFB
+	default:
+		return m.Total
+	}
+}
+
+func phasePercentiles(e *quantile.Estimator) PhasePercentiles {
+	return PhasePercentiles{
+		P50: time.Duration(e.Get(0.50)),
+		P95: time.Duration(e.Get(0.95)),
+		P99: time.Duration(e.Get(0.99)),
+	}
+}
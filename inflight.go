@@ -0,0 +1,99 @@
+package loaderbot
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// InFlightSampleInterval how often in-flight attacks are sampled
+	InFlightSampleInterval = 10 * time.Millisecond
+)
+
+// InFlightStats concurrency observed while tick attacks were scheduled
+type InFlightStats struct {
+	Tick int
+	// Samples amount of samples taken during the tick
+	Samples int
+	// InFlightMin, InFlightMean, InFlightMax Do calls in progress, including ones abandoned on timeout
+	InFlightMin  int64
+	InFlightMean float64
+	InFlightMax  int64
+	// IdleMin, IdleMean, IdleMax attackers waiting for the next attack
+	IdleMin  int64
+	IdleMean float64
+	IdleMax  int64
+
+	inFlightTotal int64
+	idleTotal     int64
+}
+
+func (s *InFlightStats) add(inFlight, idle int64) {
+	if s.Samples == 0 || inFlight < s.InFlightMin {
+		s.InFlightMin = inFlight
+	}
+	if inFlight > s.InFlightMax {
+		s.InFlightMax = inFlight
+	}
+	if s.Samples == 0 || idle < s.IdleMin {
+		s.IdleMin = idle
+	}
+	if idle > s.IdleMax {
+		s.IdleMax = idle
+	}
+	s.Samples++
+	s.inFlightTotal += inFlight
+	s.idleTotal += idle
+	s.InFlightMean = float64(s.inFlightTotal) / float64(s.Samples)
+	s.IdleMean = float64(s.idleTotal) / float64(s.Samples)
+}
+
+// InFlight amount of Do calls in progress
+func (r *Runner) InFlight() int64 {
+	return atomic.LoadInt64(&r.inFlight)
+}
+
+// IdleAttackers amount of attackers waiting for the next attack
+func (r *Runner) IdleAttackers() int64 {
+	idle := atomic.LoadInt64(&r.attackersCount) - atomic.LoadInt64(&r.busyAttackers)
+	if idle < 0 {
+		return 0
+	}
+	return idle
+}
+
+// trackInFlight samples in-flight attacks and idle attackers, aggregating them by currently scheduled tick
+func (r *Runner) trackInFlight() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(InFlightSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.TimeoutCtx.Done():
+				return
+			case <-ticker.C:
+				tick := int(atomic.LoadInt64(&r.scheduledTick))
+				r.inFlightStatsMu.Lock()
+				if _, ok := r.inFlightStats[tick]; !ok {
+					r.inFlightStats[tick] = &InFlightStats{Tick: tick}
+				}
+				r.inFlightStats[tick].add(r.InFlight(), r.IdleAttackers())
+				r.inFlightStatsMu.Unlock()
+			}
+		}
+	}()
+}
+
+// popInFlightStats returns in-flight stats of a tick, nil if tick was never sampled
+func (r *Runner) popInFlightStats(tick int) *InFlightStats {
+	r.inFlightStatsMu.Lock()
+	defer r.inFlightStatsMu.Unlock()
+	s, ok := r.inFlightStats[tick]
+	if !ok {
+		return nil
+	}
+	delete(r.inFlightStats, tick)
+	return s
+}
@@ -0,0 +1,85 @@
+package loaderbot
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonInFlightStats(t *testing.T) {
+	s := &InFlightStats{Tick: 1}
+	s.add(3, 2)
+	s.add(1, 4)
+	s.add(5, 0)
+	require.Equal(t, 3, s.Samples)
+	require.Equal(t, int64(1), s.InFlightMin)
+	require.Equal(t, 3.0, s.InFlightMean)
+	require.Equal(t, int64(5), s.InFlightMax)
+	require.Equal(t, int64(0), s.IdleMin)
+	require.Equal(t, 2.0, s.IdleMean)
+	require.Equal(t, int64(4), s.IdleMax)
+}
+
+func TestCommonIdleAttackers(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "test_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       5,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     1,
+		ReportOptions:   &ReportOptions{},
+	}, &ControlAttackerMock{}, nil)
+	require.Equal(t, int64(5), r.IdleAttackers())
+	atomic.AddInt64(&r.busyAttackers, 3)
+	require.Equal(t, int64(2), r.IdleAttackers())
+	// attacker abandoned on timeout is counted until its Do returns
+	atomic.AddInt64(&r.busyAttackers, 3)
+	require.Equal(t, int64(0), r.IdleAttackers())
+
+	r.inFlightStats[1] = &InFlightStats{Tick: 1}
+	r.inFlightStats[1].add(2, 3)
+	s := r.popInFlightStats(1)
+	require.Equal(t, int64(2), s.InFlightMax)
+	require.Nil(t, r.popInFlightStats(1))
+}
+
+func TestCommonScaleAttackers(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:                 "test_runner",
+		SystemMode:           BoundRPSAutoscale,
+		Attackers:            5,
+		AttackerTimeout:      1,
+		StartRPS:             10,
+		TestTimeSec:          1,
+		AttackersScaleAmount: 3,
+		ReportOptions:        &ReportOptions{},
+	}, &ControlAttackerMock{}, nil)
+	// scaled attackers exit when schedule is closed
+	defer close(r.next)
+	tm := &TickMetrics{
+		Samples: []AttackResult{{AttackToken: attackToken{TargetRPS: 10}}},
+		Metrics: &Metrics{Rate: 5},
+	}
+	// target rate is not met, but attackers were idle the whole tick
+	tm.InFlight = &InFlightStats{IdleMin: 1}
+	r.scaleAttackers(tm)
+	require.Len(t, r.attackers, 5)
+	require.Equal(t, int64(5), atomic.LoadInt64(&r.attackersCount))
+
+	tm.InFlight = &InFlightStats{IdleMin: 0, IdleMax: 2}
+	r.scaleAttackers(tm)
+	require.Len(t, r.attackers, 8)
+	require.Equal(t, int64(8), atomic.LoadInt64(&r.attackersCount))
+
+	// no in-flight stats, scaled by rate only
+	tm.InFlight = nil
+	r.scaleAttackers(tm)
+	require.Len(t, r.attackers, 11)
+
+	// target rate is met
+	tm.Metrics.Rate = 10
+	r.scaleAttackers(tm)
+	require.Len(t, r.attackers, 11)
+}
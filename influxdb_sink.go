@@ -0,0 +1,145 @@
+package loaderbot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxQueueSize ticks buffered while previous write is in progress, the rest are dropped
+const influxQueueSize = 100
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// InfluxDBSink writes tick metrics in InfluxDB line protocol over http or udp,
+// one line per tick, runner, node and step are tags
+type InfluxDBSink struct {
+	cfg     *InfluxDB
+	client  *http.Client
+	conn    net.Conn
+	lines   chan []byte
+	exited  chan struct{}
+	dropped int
+	L       *Logger
+}
+
+// NewInfluxDBSink creates sink writing to cfg.URL, or to cfg.UDPAddr if url is empty
+func NewInfluxDBSink(cfg *InfluxDB, l *Logger) (*InfluxDBSink, error) {
+	s := &InfluxDBSink{
+		cfg:    cfg,
+		lines:  make(chan []byte, influxQueueSize),
+		exited: make(chan struct{}),
+		L:      l,
+	}
+	switch {
+	case cfg.URL != "":
+		s.client = &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second}
+	case cfg.UDPAddr != "":
+		conn, err := net.Dial("udp", cfg.UDPAddr)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	default:
+		return nil, errors.New("no influxdb url or udp address")
+	}
+	go s.writeLoop()
+	return s, nil
+}
+
+// ReportTick queues tick line, it's dropped if writer falls behind
+func (s *InfluxDBSink) ReportTick(t SinkTick) {
+	select {
+	case s.lines <- influxLine(s.cfg.Measurement, t):
+	default:
+		s.dropped++
+	}
+}
+
+// Close writes queued lines
+func (s *InfluxDBSink) Close() error {
+	close(s.lines)
+	<-s.exited
+	if s.dropped > 0 {
+		s.L.Warnf("influxdb: %d ticks dropped, writes are too slow", s.dropped)
+	}
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func (s *InfluxDBSink) writeLoop() {
+	defer close(s.exited)
+	for line := range s.lines {
+		if err := s.write(line); err != nil {
+			s.L.Errorf("influxdb: failed to write tick: %s", err)
+		}
+	}
+}
+
+func (s *InfluxDBSink) write(line []byte) error {
+	if s.conn != nil {
+		_, err := s.conn.Write(line)
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.cfg.Token)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("influxdb responded %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// influxLine line protocol entry with ns timestamp, NaN and Inf fields are skipped as influx rejects them
+func influxLine(measurement string, t SinkTick) []byte {
+	var b bytes.Buffer
+	b.WriteString(influxMeasurementEscaper.Replace(measurement))
+	b.WriteString(",runner=")
+	b.WriteString(influxKeyEscaper.Replace(t.Runner))
+	if t.Node != AggregatedNode {
+		b.WriteString(",node=")
+		b.WriteString(influxKeyEscaper.Replace(t.Node))
+	}
+	b.WriteString(",step=")
+	b.WriteString(strconv.Itoa(t.Step))
+	b.WriteString(" tick=")
+	b.WriteString(strconv.Itoa(t.Tick))
+	b.WriteString("i")
+	for _, f := range sinkFields(t) {
+		if math.IsNaN(f.value) || math.IsInf(f.value, 0) {
+			continue
+		}
+		b.WriteString(",")
+		b.WriteString(influxKeyEscaper.Replace(f.name))
+		b.WriteString("=")
+		b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+	}
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(t.Time.UnixNano(), 10))
+	b.WriteString("\n")
+	return b.Bytes()
+}
@@ -0,0 +1,131 @@
+package loaderbot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// JUnitFile stable junit report name, latest run of a runner overwrites it
+	JUnitFile = "junit_%s.xml"
+)
+
+// JUnitTestSuites junit xml report root
+type JUnitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite run checks
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase one check, failed if Failure is set
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure check failure with measured values
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit creates junit report, thresholds, SLO objectives and success ratio of every request label are test cases
+func (r *Runner) JUnit() *JUnitTestSuites {
+	return newJUnit(r.Name, r.startTime, r.endTime, r.Thresholds(), r.LabelMetrics, r.Cfg.SuccessRatio)
+}
+
+// JUnit creates junit report of cluster run, metrics are aggregated over all nodes
+func (m *ClusterClient) JUnit() *JUnitTestSuites {
+	return newJUnit(m.testCfg.Name, m.startTime, m.endTime, m.Thresholds(), m.LabelMetrics, m.testCfg.SuccessRatio)
+}
+
+// newJUnit run thresholds take whole run time, label cases take time from first request to last response of a label
+func newJUnit(name string, start, end time.Time, thresholds []ThresholdOutcome, labelMetrics map[string]*Metrics, successRatio float64) *JUnitTestSuites {
+	suite := JUnitTestSuite{
+		Name:      name,
+		Time:      junitTime(end.Sub(start)),
+		Timestamp: start.Format("2006-01-02T15:04:05"),
+	}
+	addCase := func(caseName, class string, passed bool, message string, d time.Duration) {
+		c := JUnitTestCase{
+			Name:      caseName,
+			ClassName: name + "." + class,
+			Time:      junitTime(d),
+			SystemOut: message,
+		}
+		if !passed {
+			c.Failure = &JUnitFailure{Message: message, Type: class, Text: message}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+	}
+	for _, t := range thresholds {
+		addCase(t.Name, "thresholds", t.Passed, t.Message, end.Sub(start))
+	}
+	labels := make([]string, 0, len(labelMetrics))
+	for l := range labelMetrics {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	for _, l := range labels {
+		m := labelMetrics[l]
+		msg := fmt.Sprintf("requests %d, success ratio %.4f, threshold %.4f, p50 %v, p95 %v, p99 %v",
+			m.Requests, m.Success, successRatio, m.Latencies.P50, m.Latencies.P95, m.Latencies.P99)
+		addCase("label "+l, "labels", m.Success >= successRatio, msg, m.End.Sub(m.Earliest))
+	}
+	return &JUnitTestSuites{Suites: []JUnitTestSuite{suite}}
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// JUnitPath path of the latest run junit report
+func (r *Runner) JUnitPath() string {
+	return path.Join(r.Cfg.ReportOptions.CSVDir, fmt.Sprintf(JUnitFile, r.Name))
+}
+
+// JUnitPath path of the latest cluster run junit report
+func (m *ClusterClient) JUnitPath() string {
+	return path.Join(m.testCfg.ReportOptions.CSVDir, fmt.Sprintf(JUnitFile, m.testCfg.Name))
+}
+
+// WriteJUnit writes junit xml report, report dir is created if csv report is disabled
+func (s *JUnitTestSuites) WriteJUnit(path string) error {
+	data, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(xml.Header), data...), 0644)
+}
+
+func writeJUnit(s *JUnitTestSuites, path string, l *Logger) {
+	if err := s.WriteJUnit(path); err != nil {
+		l.Error(err)
+		return
+	}
+	l.Infof("junit report: %s", path)
+}
@@ -0,0 +1,89 @@
+package loaderbot
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonJUnitCaseTime(t *testing.T) {
+	start := time.Unix(100, 0)
+	m := NewMetrics()
+	m.add(AttackResult{Begin: start.Add(2 * time.Second), End: start.Add(2100 * time.Millisecond), DoResult: DoResult{RequestLabel: "get"}})
+	m.add(AttackResult{Begin: start.Add(3 * time.Second), End: start.Add(3500 * time.Millisecond), DoResult: DoResult{RequestLabel: "get"}})
+	m.update()
+	junit := newJUnit("run", start, start.Add(10*time.Second), thresholds(1, 1, 1, nil), map[string]*Metrics{"get": m}, 1)
+	suite := junit.Suites[0]
+	require.Equal(t, "10.000", suite.Time)
+	require.Equal(t, "success_ratio", suite.Cases[0].Name)
+	require.Equal(t, "10.000", suite.Cases[0].Time)
+	require.Equal(t, "label get", suite.Cases[1].Name)
+	require.Equal(t, "1.500", suite.Cases[1].Time)
+}
+
+func TestCommonJUnitWithoutCSV(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "junit_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     2,
+		ReportOptions: &ReportOptions{
+			CSVDir: "test_csv",
+			CSV:    false,
+			JUnit:  true,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(r.JUnitPath())
+	require.NoError(t, err)
+	var junit JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &junit))
+	require.Equal(t, 2, junit.Suites[0].Tests)
+	require.Equal(t, "label junit_runner", junit.Suites[0].Cases[1].Name)
+	require.Equal(t, r.JUnitPath(), r.Manifest(0).Artifacts["junit"])
+
+	cfg := RunnerConfig{Name: "md", Attackers: 1, AttackerTimeout: 1, TestTimeSec: 1, ReportOptions: &ReportOptions{Markdown: true}}
+	require.Contains(t, cfg.validate(), "please enable csv report, markdown report is built from percs log")
+}
+
+func TestCommonJUnitSLOFailure(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "junit_slo_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSVDir: "test_csv",
+			JUnit:  true,
+		},
+		SLO: &SLO{
+			Objectives: []LatencyObjective{{Target: 0.99, ThresholdMs: 300}},
+		},
+	}, &ControlAttackerMock{}, nil)
+	r.controlled.Sleep = 500
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(r.JUnitPath())
+	require.NoError(t, err)
+	var junit JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &junit))
+	require.Len(t, junit.Suites, 1)
+	cases := junit.Suites[0].Cases
+	require.Equal(t, 3, junit.Suites[0].Tests)
+	require.Equal(t, 1, junit.Suites[0].Failures)
+	require.Equal(t, "success_ratio", cases[0].Name)
+	require.Nil(t, cases[0].Failure)
+	require.Equal(t, "slo 99% < 300ms", cases[1].Name)
+	require.Contains(t, cases[1].Failure.Message, "compliance 0.0000")
+	require.Equal(t, "label junit_slo_runner", cases[2].Name)
+	require.Nil(t, cases[2].Failure)
+}
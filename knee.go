@@ -0,0 +1,119 @@
+package loaderbot
+
+import (
+	"fmt"
+)
+
+// KneeOptions thresholds of saturation knee detection
+type KneeOptions struct {
+	// RateRatio step is saturated when achieved rps is lower than this part of target rps
+	RateRatio float64
+	// LatencyGrowth step is saturated when p99 grows faster than target rps multiplied by this factor,
+	// relative to the last healthy step
+	LatencyGrowth float64
+	// FailureRateRatio step is failed when achieved rps is lower than this part of target rps
+	FailureRateRatio float64
+	// FailureSuccess step is failed when success ratio is lower
+	FailureSuccess float64
+}
+
+// DefaultKneeOptions default knee detection thresholds
+var DefaultKneeOptions = KneeOptions{
+	RateRatio:        0.9,
+	LatencyGrowth:    1.5,
+	FailureRateRatio: 0.5,
+	FailureSuccess:   0.9,
+}
+
+// Knee saturation analysis of a stepped run, steps are nil if not reached
+type Knee struct {
+	// LastHealthy last step in which system kept up with target rps
+	LastHealthy *StepSummary
+	// Saturation first step in which rps stopped tracking target or latency grew superlinearly
+	Saturation *StepSummary
+	// SaturationReason why the step is considered saturated
+	SaturationReason string
+	// Failure first step in which system failed to serve the load
+	Failure *StepSummary
+	// FailureReason why the step is considered failed
+	FailureReason string
+}
+
+// DetectKnee finds last healthy, saturation and failure steps, steps must be sorted
+func DetectKnee(steps []StepSummary, opts KneeOptions) *Knee {
+	k := &Knee{}
+	for i := range steps {
+		s := &steps[i]
+		if k.Failure == nil {
+			if reason := opts.failed(s); reason != "" {
+				k.Failure = s
+				k.FailureReason = reason
+			}
+		}
+		if k.Saturation == nil {
+			reason := opts.saturated(k.LastHealthy, s)
+			if reason == "" && k.Failure == nil {
+				k.LastHealthy = s
+				continue
+			}
+			if reason == "" {
+				reason = k.FailureReason
+			}
+			k.Saturation = s
+			k.SaturationReason = reason
+		}
+		if k.Failure != nil {
+			break
+		}
+	}
+	return k
+}
+
+func (o KneeOptions) failed(s *StepSummary) string {
+	if s.Success < o.FailureSuccess {
+		return fmt.Sprintf("success ratio %.2f < %.2f", s.Success, o.FailureSuccess)
+	}
+	if s.TargetRPS > 0 && s.RPS < float64(s.TargetRPS)*o.FailureRateRatio {
+		return fmt.Sprintf("rps %.2f < %.0f%% of target %d", s.RPS, o.FailureRateRatio*100, s.TargetRPS)
+	}
+	return ""
+}
+
+func (o KneeOptions) saturated(healthy, s *StepSummary) string {
+	if s.TargetRPS > 0 && s.RPS < float64(s.TargetRPS)*o.RateRatio {
+		return fmt.Sprintf("rps %.2f < %.0f%% of target %d", s.RPS, o.RateRatio*100, s.TargetRPS)
+	}
+	if healthy == nil || healthy.TargetRPS <= 0 || s.TargetRPS <= 0 {
+		return ""
+	}
+	// sub millisecond latencies are reported as zero, compare at least with 1ms
+	healthyP99 := healthy.P99
+	if healthyP99 < 1 {
+		healthyP99 = 1
+	}
+	loadGrowth := float64(s.TargetRPS) / float64(healthy.TargetRPS)
+	latencyGrowth := s.P99 / healthyP99
+	if latencyGrowth > loadGrowth*o.LatencyGrowth {
+		return fmt.Sprintf("p99 grew x%.2f while target rps grew x%.2f", latencyGrowth, loadGrowth)
+	}
+	return ""
+}
+
+func (k *Knee) String() string {
+	res := "no healthy steps"
+	if k.LastHealthy != nil {
+		res = fmt.Sprintf("last healthy step: %d, target rps: %d, rps: %.2f, p99: %.2f ms",
+			k.LastHealthy.Step, k.LastHealthy.TargetRPS, k.LastHealthy.RPS, k.LastHealthy.P99)
+	}
+	if k.Saturation != nil {
+		res += fmt.Sprintf("; saturation step: %d, target rps: %d, %s", k.Saturation.Step, k.Saturation.TargetRPS, k.SaturationReason)
+	} else {
+		res += "; saturation not reached"
+	}
+	if k.Failure != nil {
+		res += fmt.Sprintf("; failure step: %d, target rps: %d, %s", k.Failure.Step, k.Failure.TargetRPS, k.FailureReason)
+	} else {
+		res += "; failure not reached"
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package loaderbot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonDetectKnee(t *testing.T) {
+	steps := []StepSummary{
+		{Step: 1, TargetRPS: 100, StartTick: 0, RPS: 100, P99: 20, Success: 1},
+		{Step: 2, TargetRPS: 200, StartTick: 10, RPS: 199, P99: 22, Success: 1},
+		{Step: 3, TargetRPS: 300, StartTick: 20, RPS: 298, P99: 90, Success: 1},
+		{Step: 4, TargetRPS: 400, StartTick: 30, RPS: 310, P99: 300, Success: 0.99},
+		{Step: 5, TargetRPS: 500, StartTick: 40, RPS: 200, P99: 900, Success: 0.6},
+	}
+	k := DetectKnee(steps, DefaultKneeOptions)
+	require.Equal(t, 2, k.LastHealthy.Step)
+	require.Equal(t, 3, k.Saturation.Step)
+	require.Contains(t, k.SaturationReason, "p99")
+	require.Equal(t, 5, k.Failure.Step)
+
+	k = DetectKnee(steps[:2], DefaultKneeOptions)
+	require.Equal(t, 2, k.LastHealthy.Step)
+	require.Nil(t, k.Saturation)
+	require.Nil(t, k.Failure)
+
+	// failed step is also saturated
+	k = DetectKnee([]StepSummary{steps[0], steps[4]}, DefaultKneeOptions)
+	require.Equal(t, 5, k.Saturation.Step)
+	require.Equal(t, 5, k.Failure.Step)
+
+	chart, err := PercsChartWithKnee("example_csv_data/percs.csv", "Response times", k)
+	require.NoError(t, err)
+	RenderEChart(chart, "responses.html")
+}
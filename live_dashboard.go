@@ -0,0 +1,312 @@
+package loaderbot
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// liveSubscriberBuffer ticks buffered for slow dashboard client, ticks are dropped when it's full
+	liveSubscriberBuffer = 100
+	// AggregatedNode node name of ticks aggregated over all nodes or of a single runner
+	AggregatedNode = ""
+)
+
+// LiveTick tick metrics pushed to live dashboard
+type LiveTick struct {
+	// Node address of cluster node, AggregatedNode for runner and cluster totals
+	Node      string  `json:"node"`
+	Tick      int     `json:"tick"`
+	Step      int     `json:"step"`
+	TargetRPS int     `json:"target_rps"`
+	Rate      float64 `json:"rate"`
+	Requests  uint64  `json:"requests"`
+	Failed    uint64  `json:"failed"`
+	Success   float64 `json:"success"`
+	// P50, P95, P99 latency percentiles, ms
+	P50       float64 `json:"p50"`
+	P95       float64 `json:"p95"`
+	P99       float64 `json:"p99"`
+	Attackers int     `json:"attackers"`
+}
+
+func newLiveTick(node string, token attackToken, attackers int, m *Metrics) LiveTick {
+	return LiveTick{
+		Node:      node,
+		Tick:      token.Tick,
+		Step:      token.Step,
+		TargetRPS: int(m.TargetRate),
+		Rate:      m.Rate,
+		Requests:  m.Requests,
+		Failed:    m.Requests - uint64(m.success),
+		Success:   m.Success,
+		P50:       durationMs(m.Latencies.P50),
+		P95:       durationMs(m.Latencies.P95),
+		P99:       durationMs(m.Latencies.P99),
+		Attackers: attackers,
+	}
+}
+
+// liveServer serves dashboard page and pushes ticks to it with server sent events,
+// ticks published before client connected are replayed
+type liveServer struct {
+	name        string
+	srv         *http.Server
+	mu          *sync.Mutex
+	ticks       []LiveTick
+	subscribers map[chan LiveTick]struct{}
+	closed      bool
+	L           *Logger
+}
+
+func newLiveServer(name string, port int, l *Logger) *liveServer {
+	s := &liveServer{
+		name:        name,
+		mu:          &sync.Mutex{},
+		ticks:       make([]LiveTick, 0),
+		subscribers: make(map[chan LiveTick]struct{}),
+		L:           l,
+	}
+	s.srv = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: s.handler(),
+	}
+	return s
+}
+
+func (s *liveServer) handler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/", s.page)
+	m.HandleFunc("/events", s.events)
+	m.HandleFunc("/ticks", s.history)
+	return m
+}
+
+func (s *liveServer) start() {
+	go func() {
+		s.L.Infof("live dashboard: http://localhost%s", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.L.Error(err)
+		}
+	}()
+}
+
+// publish stores tick and sends it to every connected client without blocking
+func (s *liveServer) publish(t LiveTick) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.ticks = append(s.ticks, t)
+	for ch := range s.subscribers {
+		select {
+		case ch <- t:
+		default:
+		}
+	}
+}
+
+// subscribe registers client, returns its channel and ticks published before
+func (s *liveServer) subscribe() (chan LiveTick, []LiveTick) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan LiveTick, liveSubscriberBuffer)
+	if s.closed {
+		close(ch)
+	} else {
+		s.subscribers[ch] = struct{}{}
+	}
+	return ch, append([]LiveTick(nil), s.ticks...)
+}
+
+func (s *liveServer) published() []LiveTick {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LiveTick(nil), s.ticks...)
+}
+
+func (s *liveServer) unsubscribe(ch chan LiveTick) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close ends event streams, so clients know run is finished, and stops server
+func (s *liveServer) close() {
+	s.mu.Lock()
+	s.closed = true
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.L.Error(err)
+	}
+}
+
+func (s *liveServer) events(w http.ResponseWriter, req *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	ch, history := s.subscribe()
+	defer s.unsubscribe(ch)
+	for _, t := range history {
+		if err := writeLiveEvent(w, t); err != nil {
+			return
+		}
+	}
+	f.Flush()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case t, ok := <-ch:
+			if !ok {
+				_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+				f.Flush()
+				return
+			}
+			if err := writeLiveEvent(w, t); err != nil {
+				return
+			}
+			f.Flush()
+		}
+	}
+}
+
+func writeLiveEvent(w http.ResponseWriter, t LiveTick) error {
+	d, err := jsoniter.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: tick\ndata: %s\n\n", d)
+	return err
+}
+
+func (s *liveServer) history(w http.ResponseWriter, _ *http.Request) {
+	ticks := s.published()
+	w.Header().Set("Content-Type", "application/json")
+	if err := jsoniter.NewEncoder(w).Encode(ticks); err != nil {
+		s.L.Error(err)
+	}
+}
+
+func (s *liveServer) page(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := livePageTemplate.Execute(w, s.name); err != nil {
+		s.L.Error(err)
+	}
+}
+
+var livePageTemplate = template.Must(template.New("live").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+canvas { border: 1px solid #ccc; margin: 0 10px 10px 0; }
+#status { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.}}</h1>
+<p>View: <select id="node"><option value="">aggregated</option></select> <span id="status">connecting</span></p>
+<div id="charts"></div>
+<script>
+const colors = ["#5470c6", "#91cc75", "#ee6666", "#fac858"];
+const charts = [
+  {title: "Latency, ms", series: [["p50", t => t.p50], ["p95", t => t.p95], ["p99", t => t.p99]]},
+  {title: "Rate, rps", series: [["achieved", t => t.rate], ["target", t => t.target_rps]]},
+  {title: "Failed requests", series: [["failed", t => t.failed]]},
+  {title: "Attackers", series: [["attackers", t => t.attackers]]},
+];
+const ticks = {"": []};
+const select = document.getElementById("node");
+const statusEl = document.getElementById("status");
+for (const c of charts) {
+  c.canvas = document.createElement("canvas");
+  c.canvas.width = 600;
+  c.canvas.height = 260;
+  document.getElementById("charts").appendChild(c.canvas);
+}
+function draw() {
+  const data = ticks[select.value] || [];
+  for (const c of charts) {
+    const ctx = c.canvas.getContext("2d");
+    const w = c.canvas.width, h = c.canvas.height, pad = 40;
+    ctx.clearRect(0, 0, w, h);
+    ctx.fillStyle = "#000";
+    ctx.fillText(c.title, pad, 15);
+    if (data.length === 0) continue;
+    const minX = data[0].tick, maxX = Math.max(data[data.length - 1].tick, minX + 1);
+    let maxY = 1;
+    for (const t of data) for (const s of c.series) maxY = Math.max(maxY, s[1](t));
+    maxY *= 1.1;
+    ctx.strokeStyle = "#999";
+    ctx.beginPath();
+    ctx.moveTo(pad, pad / 2);
+    ctx.lineTo(pad, h - pad);
+    ctx.lineTo(w - pad, h - pad);
+    ctx.stroke();
+    ctx.fillText(maxY.toFixed(1), 2, pad / 2 + 10);
+    ctx.fillText(minX, pad, h - pad + 15);
+    ctx.fillText(maxX + " sec", w - pad - 20, h - pad + 15);
+    c.series.forEach((s, i) => {
+      ctx.strokeStyle = colors[i];
+      ctx.fillStyle = colors[i];
+      ctx.fillText(s[0], pad + 10 + i * 80, h - 5);
+      ctx.beginPath();
+      data.forEach((t, j) => {
+        const x = pad + (t.tick - minX) / (maxX - minX) * (w - 2 * pad);
+        const y = h - pad - s[1](t) / maxY * (h - 1.5 * pad);
+        if (j === 0) ctx.moveTo(x, y); else ctx.lineTo(x, y);
+      });
+      ctx.stroke();
+    });
+  }
+}
+select.onchange = draw;
+const source = new EventSource("events");
+source.onopen = () => { statusEl.textContent = "live"; };
+source.addEventListener("tick", e => {
+  const t = JSON.parse(e.data);
+  if (!(t.node in ticks)) {
+    ticks[t.node] = [];
+    const o = document.createElement("option");
+    o.value = o.textContent = t.node;
+    select.appendChild(o);
+  }
+  ticks[t.node].push(t);
+  if (t.node === select.value) draw();
+});
+source.addEventListener("end", () => {
+  statusEl.textContent = "run finished";
+  source.close();
+});
+source.onerror = () => { statusEl.textContent = "disconnected"; };
+</script>
+</body>
+</html>
+`))
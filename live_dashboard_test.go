@@ -0,0 +1,102 @@
+package loaderbot
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func readLiveEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestCommonLiveDashboardEvents(t *testing.T) {
+	cfg := &RunnerConfig{Name: "live"}
+	cfg.DefaultCfgValues()
+	s := newLiveServer("live", 0, NewLogger(cfg))
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	s.publish(LiveTick{Tick: 1, Rate: 10})
+	s.publish(LiveTick{Node: "node1:50051", Tick: 1, Rate: 5})
+
+	res, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	r := bufio.NewReader(res.Body)
+	// published ticks are replayed to new client
+	for _, node := range []string{AggregatedNode, "node1:50051"} {
+		event, data := readLiveEvent(t, r)
+		require.Equal(t, "tick", event)
+		var tick LiveTick
+		require.NoError(t, jsoniter.Unmarshal([]byte(data), &tick))
+		require.Equal(t, node, tick.Node)
+	}
+	s.publish(LiveTick{Tick: 2, Rate: 12})
+	event, data := readLiveEvent(t, r)
+	require.Equal(t, "tick", event)
+	require.Contains(t, data, `"tick":2`)
+
+	ticks, err := http.Get(srv.URL + "/ticks")
+	require.NoError(t, err)
+	defer ticks.Body.Close()
+	var history []LiveTick
+	require.NoError(t, jsoniter.NewDecoder(ticks.Body).Decode(&history))
+	require.Len(t, history, 3)
+
+	page, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(page.Body)
+	_ = page.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), "<title>live</title>")
+	require.Contains(t, string(body), `new EventSource("events")`)
+
+	s.close()
+	event, _ = readLiveEvent(t, r)
+	require.Equal(t, "end", event)
+}
+
+func TestCommonLiveDashboardRunner(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "live_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     4,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		LiveDashboard: &LiveDashboard{Enable: true, Port: 2199},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	ticks := r.live.published()
+	require.NotEmpty(t, ticks)
+	require.Equal(t, AggregatedNode, ticks[0].Node)
+	require.Equal(t, 10, ticks[0].TargetRPS)
+	require.Equal(t, 10, ticks[0].Attackers)
+	require.Equal(t, uint64(10), ticks[0].Requests)
+}
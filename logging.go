@@ -10,6 +10,7 @@ import (
 
 type Logger struct {
 	*zap.SugaredLogger
+	level zap.AtomicLevel
 }
 
 func (m *Logger) With(args ...interface{}) *Logger {
@@ -21,6 +22,16 @@ func (m *Logger) Clone() Logger {
 	return *m
 }
 
+// Level current level of the logger
+func (m *Logger) Level() zapcore.Level {
+	return m.level.Level()
+}
+
+// SetLevel changes level of the logger and loggers derived from it
+func (m *Logger) SetLevel(level zapcore.Level) {
+	m.level.SetLevel(level)
+}
+
 func setupLogger(encoding string, level string) *Logger {
 	rawJSON := []byte(fmt.Sprintf(`{
 	  "level": "%s",
@@ -48,7 +59,7 @@ func setupLogger(encoding string, level string) *Logger {
 		panic(err)
 	}
 	_ = logger.Sync()
-	return &Logger{logger.Sugar()}
+	return &Logger{logger.Sugar(), cfg.Level}
 }
 
 func NewLogger(cfg *RunnerConfig) *Logger {
@@ -0,0 +1,250 @@
+package loaderbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/copier"
+)
+
+const (
+	// ManifestFile stable manifest name, latest run of a runner overwrites it
+	ManifestFile = "manifest_%s.json"
+	// redacted replaces credentials in manifest config
+	redacted = "<redacted>"
+)
+
+// RunManifest machine readable description of a run and its artifacts
+type RunManifest struct {
+	RunID string `json:"run_id"`
+	Name  string `json:"name"`
+	// Config run config, credentials are redacted
+	Config    *RunnerConfig `json:"config"`
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Host      string        `json:"host"`
+	GoVersion string        `json:"go_version"`
+	// Artifacts paths of run reports by kind
+	Artifacts  map[string]string  `json:"artifacts"`
+	Summary    RunSummary         `json:"summary"`
+	Thresholds []ThresholdOutcome `json:"thresholds"`
+	Passed     bool               `json:"passed"`
+	// FailureReason why run failed, empty if passed
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// RunSummary summary metrics of the whole run
+type RunSummary struct {
+	Requests         uint64         `json:"requests"`
+	Success          float64        `json:"success"`
+	MaxRPS           float64        `json:"max_rps"`
+	Latencies        LatencyMetrics `json:"latencies"`
+	StatusCodes      map[string]int `json:"status_codes"`
+	Errors           map[string]int `json:"errors"`
+	MissedSlots      int64          `json:"missed_slots"`
+	SaturatedSamples int64          `json:"saturated_samples"`
+	// Trustworthy false if load generator was saturated, nil if unknown, cluster nodes monitor their generators themselves
+	Trustworthy *bool      `json:"trustworthy,omitempty"`
+	Knee        *Knee      `json:"knee,omitempty"`
+	SLO         *SLOReport `json:"slo,omitempty"`
+}
+
+// untrustworthy true only if load generator is known to be saturated
+func (s RunSummary) untrustworthy() bool {
+	return s.Trustworthy != nil && !*s.Trustworthy
+}
+
+// trustworthyString true, false or unknown
+func (s RunSummary) trustworthyString() string {
+	if s.Trustworthy == nil {
+		return "unknown"
+	}
+	return strconv.FormatBool(*s.Trustworthy)
+}
+
+// ThresholdOutcome result of a run check against configured threshold
+type ThresholdOutcome struct {
+	Name      string  `json:"name"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	Passed    bool    `json:"passed"`
+	Message   string  `json:"message"`
+}
+
+// Thresholds checks run against success ratio and SLO objectives
+func (r *Runner) Thresholds() []ThresholdOutcome {
+	return thresholds(r.Cfg.SuccessRatio, r.minTickSuccess, r.ticksReported, r.SLO)
+}
+
+func thresholds(successRatio, minTickSuccess float64, ticksReported int, slo *SLOReport) []ThresholdOutcome {
+	res := make([]ThresholdOutcome, 0)
+	if ticksReported == 0 {
+		minTickSuccess = 1
+	}
+	o := ThresholdOutcome{
+		Name:      "success_ratio",
+		Threshold: successRatio,
+		Value:     minTickSuccess,
+		Passed:    minTickSuccess >= successRatio,
+	}
+	o.Message = fmt.Sprintf("min tick success ratio %.4f, threshold %.4f", o.Value, o.Threshold)
+	res = append(res, o)
+	if slo != nil {
+		for _, obj := range slo.Run.Objectives {
+			res = append(res, ThresholdOutcome{
+				Name:      "slo " + obj.Name,
+				Threshold: obj.Target,
+				Value:     obj.Compliance,
+				Passed:    obj.Met,
+				Message: fmt.Sprintf("compliance %.4f, target %.4f, good %d, bad %d, error budget burn %.4f",
+					obj.Compliance, obj.Target, obj.Good, obj.Bad, obj.BudgetBurn),
+			})
+		}
+	}
+	return res
+}
+
+// Manifest describes finished run
+func (r *Runner) Manifest(maxRPS float64) *RunManifest {
+	host, _ := os.Hostname()
+	trustworthy := r.Trustworthy()
+	m := &RunManifest{
+		Name:      r.Name,
+		Config:    redactConfig(r.Cfg),
+		Start:     r.startTime,
+		End:       r.endTime,
+		Host:      host,
+		GoVersion: runtime.Version(),
+		Artifacts: map[string]string{},
+		Summary: RunSummary{
+			Requests:         r.RunMetrics.Requests,
+			Success:          r.RunMetrics.Success,
+			MaxRPS:           maxRPS,
+			Latencies:        r.RunMetrics.Latencies,
+			StatusCodes:      r.RunMetrics.StatusCodes,
+			Errors:           r.uniqErrors,
+			MissedSlots:      r.MissedSlots,
+			SaturatedSamples: r.SaturatedSamples,
+			Trustworthy:      &trustworthy,
+			Knee:             r.Knee,
+			SLO:              r.SLO,
+		},
+		Thresholds:    r.Thresholds(),
+		FailureReason: r.failureReason,
+	}
+	if r.Report != nil {
+		m.RunID = r.Report.runId
+		m.Artifacts = r.Report.artifacts()
+		if r.Cfg.ReportOptions.Markdown {
+			m.Artifacts["markdown"] = r.MarkdownReportPath()
+		}
+	}
+	if r.Cfg.ReportOptions.JUnit {
+		m.Artifacts["junit"] = r.JUnitPath()
+	}
+	m.check()
+	return m
+}
+
+// redactConfig copy of config without credentials, manifest is published as a CI artifact
+func redactConfig(cfg *RunnerConfig) *RunnerConfig {
+	var c RunnerConfig
+	if err := copier.Copy(&c, cfg); err != nil {
+		return nil
+	}
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	if c.Slack != nil {
+		redact(&c.Slack.WebhookURL)
+		redact(&c.Slack.BotToken)
+	}
+	if c.Elasticsearch != nil {
+		redact(&c.Elasticsearch.Password)
+		redact(&c.Elasticsearch.APIKey)
+	}
+	if c.InfluxDB != nil {
+		redact(&c.InfluxDB.Token)
+	}
+	if c.OpenTelemetry != nil && c.OpenTelemetry.Headers != nil {
+		headers := make(map[string]string, len(c.OpenTelemetry.Headers))
+		for k := range c.OpenTelemetry.Headers {
+			headers[k] = redacted
+		}
+		c.OpenTelemetry.Headers = headers
+	}
+	return &c
+}
+
+// check sets verdict of a run, first failed threshold is a failure reason if run wasn't stopped earlier
+func (m *RunManifest) check() {
+	m.Passed = true
+	for _, t := range m.Thresholds {
+		if !t.Passed {
+			m.Passed = false
+			if m.FailureReason == "" {
+				m.FailureReason = fmt.Sprintf("%s: %s", t.Name, t.Message)
+			}
+		}
+	}
+	if m.FailureReason != "" {
+		m.Passed = false
+	}
+}
+
+// ManifestPath path of the latest run manifest
+func (r *Runner) ManifestPath() string {
+	return path.Join(r.Cfg.ReportOptions.CSVDir, fmt.Sprintf(ManifestFile, r.Name))
+}
+
+// ManifestPath path of the latest cluster run manifest
+func (m *ClusterClient) ManifestPath() string {
+	return path.Join(m.testCfg.ReportOptions.CSVDir, fmt.Sprintf(ManifestFile, m.testCfg.Name))
+}
+
+// MarkdownReportPath path of the latest run markdown report
+func (r *Runner) MarkdownReportPath() string {
+	return path.Join(r.Cfg.ReportOptions.CSVDir, fmt.Sprintf(MarkdownReportFile, r.Name))
+}
+
+// WriteManifest writes manifest as indented json
+func (m *RunManifest) WriteManifest(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func writeManifest(m *RunManifest, path string, l *Logger) {
+	if err := m.WriteManifest(path); err != nil {
+		l.Error(err)
+		return
+	}
+	l.Infof("run manifest: %s", path)
+}
+
+// LoadManifest reads run manifest
+func LoadManifest(path string) (*RunManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m RunManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
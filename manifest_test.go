@@ -0,0 +1,125 @@
+package loaderbot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonManifestWithoutCSV(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "manifest_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     2,
+		ReportOptions: &ReportOptions{
+			CSVDir: "test_csv",
+			CSV:    false,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	m, err := LoadManifest(r.ManifestPath())
+	require.NoError(t, err)
+	require.Equal(t, "manifest_runner", m.Name)
+	require.True(t, m.Passed)
+	require.Equal(t, r.RunMetrics.Requests, m.Summary.Requests)
+	require.True(t, *m.Summary.Trustworthy)
+	require.Empty(t, m.Artifacts)
+}
+
+func TestCommonManifest(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "manifest_csv_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			HTMLDir: "test_html",
+			CSVDir:  "test_csv",
+			CSV:     true,
+			PNG:     true,
+		},
+		SLO: &SLO{
+			Objectives: []LatencyObjective{{Target: 0.99, ThresholdMs: 300}},
+		},
+	}, &ControlAttackerMock{}, nil)
+	r.controlled.Sleep = 500
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	m, err := LoadManifest(r.ManifestPath())
+	require.NoError(t, err)
+	require.Equal(t, r.Report.runId, m.RunID)
+	require.Equal(t, "test_csv", m.Config.ReportOptions.CSVDir)
+	require.Equal(t, r.RunMetrics.Requests, m.Summary.Requests)
+	require.FileExists(t, m.Artifacts["percs"])
+	require.FileExists(t, m.Artifacts["html"])
+	require.Len(t, m.Thresholds, 2)
+	require.False(t, m.Passed)
+	require.Contains(t, m.FailureReason, "slo 99% < 300ms")
+}
+
+func TestCommonManifestRedactsSecrets(t *testing.T) {
+	secrets := []string{"hooks.slack.com/services/secret", "xoxb-secret", "es-password", "es-api-key", "influx-token", "Bearer otel-secret"}
+	cfg := &RunnerConfig{
+		Name:            "secrets_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       1,
+		AttackerTimeout: 1,
+		StartRPS:        1,
+		TestTimeSec:     1,
+		ReportOptions:   &ReportOptions{CSVDir: "test_csv"},
+		Slack:           &Slack{WebhookURL: "https://" + secrets[0], BotToken: secrets[1], Channel: "C1"},
+		Elasticsearch:   &Elasticsearch{URL: "http://localhost:9200", Username: "elastic", Password: secrets[2], APIKey: secrets[3]},
+		InfluxDB:        &InfluxDB{URL: "http://localhost:8086/write?db=loaderbot", Token: secrets[4]},
+		OpenTelemetry:   &OpenTelemetry{Endpoint: "http://localhost:4318", Headers: map[string]string{"Authorization": secrets[5]}},
+	}
+	r := NewRunner(cfg, &ControlAttackerMock{}, nil)
+	defer r.es.Close()
+	m := r.Manifest(0)
+	require.NoError(t, m.WriteManifest(path.Join("test_csv", "manifest_secrets_runner.json")))
+	data, err := ioutil.ReadFile(path.Join("test_csv", "manifest_secrets_runner.json"))
+	require.NoError(t, err)
+	for _, s := range secrets {
+		require.NotContains(t, string(data), s)
+	}
+	loaded, err := LoadManifest(path.Join("test_csv", "manifest_secrets_runner.json"))
+	require.NoError(t, err)
+	require.Equal(t, "elastic", loaded.Config.Elasticsearch.Username)
+	require.Equal(t, redacted, loaded.Config.OpenTelemetry.Headers["Authorization"])
+	// run config is not changed
+	require.Equal(t, secrets[1], cfg.Slack.BotToken)
+	require.Equal(t, secrets[5], cfg.OpenTelemetry.Headers["Authorization"])
+}
+
+func TestCommonManifestNotWrittenByNode(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "node_manifest_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     2,
+		ReportOptions: &ReportOptions{
+			CSVDir: "test_csv",
+			Stream: true,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_ = os.Remove(r.ManifestPath())
+	go func() {
+		for range r.OutResults {
+		}
+	}()
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	// results are streamed to cluster client, it writes manifest of the whole run
+	require.NoFileExists(t, r.ManifestPath())
+}
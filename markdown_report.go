@@ -0,0 +1,209 @@
+package loaderbot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MarkdownReportFile stable markdown report name, latest run of a runner overwrites it
+	MarkdownReportFile = "report_%s.md"
+	// markdownTopErrors amount of most frequent errors in report
+	markdownTopErrors = 10
+)
+
+// MarkdownReport run summary to post on pull requests
+type MarkdownReport struct {
+	Manifest *RunManifest
+	// Run per step results
+	Run *RunData
+	// Comparison with baseline, nil if no baseline is set
+	Comparison *Comparison
+	// ArtifactsDir artifacts links are relative to it, paths are kept as is if empty
+	ArtifactsDir string
+}
+
+// newMarkdownReport loads per step results of a run from percs log and compares them with baseline if it's set
+func (r *Report) newMarkdownReport(m *RunManifest, baseline string) (*MarkdownReport, error) {
+	run, err := LoadRunCSV(r.percLogFilename)
+	if err != nil {
+		return nil, err
+	}
+	run.Name = r.runName
+	mr := &MarkdownReport{
+		Manifest:     m,
+		Run:          run,
+		ArtifactsDir: r.reportOptions.CSVDir,
+	}
+	if baseline != "" {
+		base, err := LoadRun(baseline)
+		if err != nil {
+			return nil, err
+		}
+		mr.Comparison = Compare(base, []*RunData{run}, CompareOptions{Tolerances: DefaultTolerances})
+	}
+	return mr, nil
+}
+
+// Markdown renders report: config, summary, steps, thresholds, top errors, artifacts and comparison with baseline
+func (mr *MarkdownReport) Markdown() string {
+	m := mr.Manifest
+	var b strings.Builder
+	passed := m.Passed
+	if mr.Comparison != nil {
+		passed = passed && mr.Comparison.Passed
+	}
+	fmt.Fprintf(&b, "# Load test `%s`: %s\n\n", m.Name, verdict(passed))
+	if m.FailureReason != "" {
+		fmt.Fprintf(&b, "Failure reason: %s\n\n", escapeMarkdownCell(m.FailureReason))
+	}
+
+	b.WriteString("## Configuration\n\n")
+	b.WriteString("| parameter | value |\n|---|---|\n")
+	for _, row := range mr.configRows() {
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], escapeMarkdownCell(row[1]))
+	}
+	b.WriteString("\n")
+
+	s := m.Summary
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| requests | success | max rps | p50 | p95 | p99 | max | trustworthy |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %.4f | %.2f | %v | %v | %v | %v | %s |\n\n",
+		s.Requests, s.Success, s.MaxRPS,
+		s.Latencies.P50, s.Latencies.P95, s.Latencies.P99, s.Latencies.Max,
+		s.trustworthyString(),
+	)
+	if s.Knee != nil {
+		fmt.Fprintf(&b, "Knee: %s\n\n", s.Knee)
+	}
+	if s.SLO != nil {
+		fmt.Fprintf(&b, "SLO: %s\n\n", s.SLO.Run)
+	}
+
+	if mr.Run != nil && len(mr.Run.Steps) > 0 {
+		b.WriteString("## Steps\n\n")
+		b.WriteString("| step | target rps | ticks | rps | p50, ms | p95, ms | p99, ms | success |\n")
+		b.WriteString("|---|---|---|---|---|---|---|---|\n")
+		for _, st := range mr.Run.Steps {
+			fmt.Fprintf(&b, "| %d | %d | %d | %.2f | %.2f | %.2f | %.2f | %.4f |\n",
+				st.Step, st.TargetRPS, st.Ticks, st.RPS, st.P50, st.P95, st.P99, st.Success)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Thresholds\n\n")
+	b.WriteString("| check | threshold | value | verdict | details |\n|---|---|---|---|---|\n")
+	for _, t := range m.Thresholds {
+		fmt.Fprintf(&b, "| %s | %.4f | %.4f | %s | %s |\n",
+			escapeMarkdownCell(t.Name), t.Threshold, t.Value, verdict(t.Passed), escapeMarkdownCell(t.Message))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Top errors\n\n")
+	errs := topErrors(s.Errors, markdownTopErrors)
+	if len(errs) == 0 {
+		b.WriteString("No errors\n\n")
+	} else {
+		b.WriteString("| count | error |\n|---|---|\n")
+		for _, e := range errs {
+			fmt.Fprintf(&b, "| %d | %s |\n", s.Errors[e], escapeMarkdownCell(e))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(m.Artifacts) > 0 {
+		b.WriteString("## Artifacts\n\n")
+		kinds := make([]string, 0, len(m.Artifacts))
+		for k := range m.Artifacts {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for _, k := range kinds {
+			fmt.Fprintf(&b, "- [%s](%s)\n", k, mr.artifactLink(m.Artifacts[k]))
+		}
+		b.WriteString("\n")
+	}
+
+	if mr.Comparison != nil {
+		b.WriteString(mr.Comparison.Markdown())
+	}
+	return b.String()
+}
+
+// WriteMarkdown writes markdown report
+func (mr *MarkdownReport) WriteMarkdown(path string) error {
+	return ioutil.WriteFile(path, []byte(mr.Markdown()), 0644)
+}
+
+func (mr *MarkdownReport) configRows() [][2]string {
+	m := mr.Manifest
+	c := m.Config
+	rows := [][2]string{{"Run id", m.RunID}}
+	if c != nil {
+		rows = append(rows,
+			[2]string{"Mode", c.SystemMode.String()},
+			[2]string{"Target", c.TargetUrl},
+			[2]string{"Attackers", strconv.Itoa(c.Attackers)},
+		)
+		if c.SystemMode != UnboundRPS {
+			rows = append(rows,
+				[2]string{"Start rps", strconv.Itoa(c.StartRPS)},
+				[2]string{"Step rps", strconv.Itoa(c.StepRPS)},
+				[2]string{"Step duration", (time.Duration(c.StepDurationSec) * time.Second).String()},
+			)
+		}
+		rows = append(rows,
+			[2]string{"Test time", (time.Duration(c.TestTimeSec) * time.Second).String()},
+			[2]string{"Success ratio", formatFloat(c.SuccessRatio)},
+		)
+		if c.ClusterOptions != nil && len(c.ClusterOptions.Nodes) > 0 {
+			rows = append(rows, [2]string{"Nodes", strings.Join(c.ClusterOptions.Nodes, ", ")})
+		}
+	}
+	rows = append(rows,
+		[2]string{"Start", m.Start.Format(time.RFC3339)},
+		[2]string{"Duration", m.End.Sub(m.Start).Truncate(time.Millisecond).String()},
+		[2]string{"Host", m.Host},
+		[2]string{"Go", m.GoVersion},
+	)
+	return rows
+}
+
+func (mr *MarkdownReport) artifactLink(path string) string {
+	if mr.ArtifactsDir == "" {
+		return path
+	}
+	rel, err := filepath.Rel(mr.ArtifactsDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// topErrors most frequent errors first
+func topErrors(errors map[string]int, limit int) []string {
+	res := make([]string, 0, len(errors))
+	for e := range errors {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if errors[res[i]] != errors[res[j]] {
+			return errors[res[i]] > errors[res[j]]
+		}
+		return res[i] < res[j]
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,99 @@
+package loaderbot
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonMarkdownReport(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &RunnerConfig{
+		Name:           "md",
+		SystemMode:     BoundRPS,
+		TargetUrl:      "http://localhost:9031/json_body",
+		Attackers:      10,
+		StartRPS:       10,
+		StepRPS:        5,
+		TestTimeSec:    60,
+		SuccessRatio:   0.95,
+		ClusterOptions: &ClusterOptions{Nodes: []string{"node1:50051", "node2:50051"}},
+	}
+	m := &RunManifest{
+		RunID:     "run",
+		Name:      "md",
+		Config:    cfg,
+		Start:     start,
+		End:       start.Add(61500 * time.Millisecond),
+		Artifacts: map[string]string{"percs": "results_csv/percs_md.csv", "html": "results_html/md.html"},
+		Summary: RunSummary{
+			Requests: 100,
+			Success:  0.9,
+			Errors:   map[string]int{"timeout": 2, "bad | response": 8},
+		},
+		Thresholds: thresholds(cfg.SuccessRatio, 0.5, 10, nil),
+	}
+	m.check()
+	mr := &MarkdownReport{
+		Manifest: m,
+		Run: &RunData{
+			Name:  "md",
+			Steps: []StepSummary{{Step: 1, TargetRPS: 10, Ticks: 5, RPS: 10, P50: 1, P95: 2, P99: 3, Success: 0.9}},
+		},
+		ArtifactsDir: "results_csv",
+	}
+	md := mr.Markdown()
+	require.True(t, strings.HasPrefix(md, "# Load test `md`: FAILED\n"))
+	require.Contains(t, md, "Failure reason: ")
+	require.Contains(t, md, "| Nodes | node1:50051, node2:50051 |")
+	require.Contains(t, md, "| Duration | 1m1.5s |")
+	// cluster runs don't know if node generators were saturated
+	require.Contains(t, md, "| 100 | 0.9000 | 0.00 | 0s | 0s | 0s | 0s | unknown |")
+	require.Contains(t, md, "| 1 | 10 | 5 | 10.00 | 1.00 | 2.00 | 3.00 | 0.9000 |")
+	require.Contains(t, md, "| success_ratio | 0.9500 | 0.5000 | FAILED |")
+	require.Contains(t, md, "| 8 | bad \\| response |\n| 2 | timeout |")
+	require.Contains(t, md, "- [percs](percs_md.csv)")
+	require.Contains(t, md, "- [html](../results_html/md.html)")
+	require.NotContains(t, md, "Comparison")
+}
+
+func TestCommonMarkdownReportRunner(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "md_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		StepDurationSec: 2,
+		StepRPS:         2,
+		TestTimeSec:     4,
+		ReportOptions: &ReportOptions{
+			HTMLDir:  "test_html",
+			CSVDir:   "test_csv",
+			CSV:      true,
+			Markdown: true,
+			Baseline: "example_csv_data/percs.csv",
+		},
+		SLO: &SLO{
+			Objectives: []LatencyObjective{{Target: 0.99, ThresholdMs: 300}},
+		},
+	}, &ControlAttackerMock{}, nil)
+	r.controlled.Sleep = 500
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	m := r.Manifest(0)
+	require.Equal(t, r.MarkdownReportPath(), m.Artifacts["markdown"])
+	md, err := ioutil.ReadFile(r.MarkdownReportPath())
+	require.NoError(t, err)
+	require.Contains(t, string(md), "# Load test `md_runner`: FAILED")
+	require.Contains(t, string(md), "| Attackers | 10 |")
+	require.Contains(t, string(md), "## Steps")
+	require.Contains(t, string(md), "| slo 99% < 300ms |")
+	require.Contains(t, string(md), "- [percs]("+filepath.Base(m.Artifacts["percs"])+")")
+	require.Contains(t, string(md), "## Comparison with baseline")
+}
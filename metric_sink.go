@@ -0,0 +1,125 @@
+package loaderbot
+
+import (
+	"sort"
+	"time"
+)
+
+// MetricSink receives metrics of every reported tick of a runner or aggregated over a cluster,
+// Runner.Sinks has PromReporter and sinks from config, ClusterClient.Sinks has sinks from config,
+// append custom sinks before run
+type MetricSink interface {
+	// ReportTick is called once per tick, must not block for long
+	ReportTick(t SinkTick)
+	// Close flushes buffered metrics when run ends
+	Close() error
+}
+
+// SinkTick tick metrics passed to sinks
+type SinkTick struct {
+	// Runner run name
+	Runner string
+	// Node cluster node, AggregatedNode for a single runner or cluster aggregate
+	Node      string
+	Step      int
+	Tick      int
+	Attackers int
+	Time      time.Time
+	Metrics   *Metrics
+	// Scheduler nil in UnboundRPS mode and for cluster ticks
+	Scheduler *SchedulerTickStats
+	// InFlight nil for cluster ticks
+	InFlight *InFlightStats
+}
+
+type sinkField struct {
+	name  string
+	value float64
+}
+
+// sinkFields flat tick metrics shared by text protocol sinks, latencies are in ms
+func sinkFields(t SinkTick) []sinkField {
+	m := t.Metrics
+	fields := []sinkField{
+		{"rps", m.Rate},
+		{"target_rps", m.TargetRate},
+		{"requests", float64(m.Requests)},
+		{"success_ratio", m.Success},
+		{"p50_ms", durationMs(m.Latencies.P50)},
+		{"p95_ms", durationMs(m.Latencies.P95)},
+		{"p99_ms", durationMs(m.Latencies.P99)},
+		{"max_ms", durationMs(m.Latencies.Max)},
+		{"attackers", float64(t.Attackers)},
+	}
+	if s := t.Scheduler; s != nil {
+		fields = append(fields,
+			sinkField{"missed_slots", float64(s.Missed)},
+			sinkField{"lag_max_ms", durationMs(s.LagMax)},
+		)
+	}
+	if s := t.InFlight; s != nil {
+		fields = append(fields, sinkField{"in_flight_mean", s.InFlightMean})
+	}
+	names := make([]string, 0, len(m.CustomMetrics))
+	for name := range m.CustomMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cm := m.CustomMetrics[name]
+		prefix := "custom_" + name + "_"
+		switch cm.Type {
+		case CustomCounter:
+			fields = append(fields, sinkField{prefix + "sum", cm.Sum})
+		case CustomGauge:
+			fields = append(fields,
+				sinkField{prefix + "last", cm.Last},
+				sinkField{prefix + "mean", cm.Mean},
+				sinkField{prefix + "max", cm.Max},
+			)
+		case CustomTiming:
+			fields = append(fields,
+				sinkField{prefix + "p50", cm.P50},
+				sinkField{prefix + "p95", cm.P95},
+				sinkField{prefix + "p99", cm.P99},
+			)
+		}
+	}
+	return fields
+}
+
+// newMetricSinks sinks configured in RunnerConfig
+func newMetricSinks(cfg *RunnerConfig, l *Logger) []MetricSink {
+	sinks := make([]MetricSink, 0)
+	if cfg.InfluxDB != nil {
+		s, err := NewInfluxDBSink(cfg.InfluxDB, l)
+		if err != nil {
+			l.Errorf("influxdb sink is disabled: %s", err)
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+	if cfg.StatsD != nil {
+		s, err := NewStatsDSink(cfg.StatsD)
+		if err != nil {
+			l.Errorf("statsd sink is disabled: %s", err)
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks
+}
+
+func reportSinks(sinks []MetricSink, t SinkTick) {
+	for _, s := range sinks {
+		s.ReportTick(t)
+	}
+}
+
+func closeSinks(sinks []MetricSink, l *Logger) {
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			l.Errorf("failed to close metric sink: %s", err)
+		}
+	}
+}
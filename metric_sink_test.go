@@ -0,0 +1,123 @@
+package loaderbot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSinkTick() SinkTick {
+	m := NewMetrics()
+	m.Rate = 10.5
+	m.TargetRate = 10
+	m.Requests = 10
+	m.Success = 1
+	m.Latencies.P50 = 2 * time.Millisecond
+	m.CustomMetrics = map[string]*CustomMetricSummary{"queue": {Type: CustomCounter, Sum: 3}}
+	return SinkTick{
+		Runner:    "sink runner",
+		Node:      AggregatedNode,
+		Step:      1,
+		Tick:      2,
+		Attackers: 5,
+		Time:      time.Unix(0, 42),
+		Metrics:   m,
+	}
+}
+
+func TestCommonInfluxLine(t *testing.T) {
+	require.Equal(t,
+		`loaderbot,runner=sink\ runner,step=1 tick=2i,rps=10.5,target_rps=10,requests=10,success_ratio=1,p50_ms=2,p95_ms=0,p99_ms=0,max_ms=0,attackers=5,custom_queue_sum=3 42`+"\n",
+		string(influxLine("loaderbot", testSinkTick())),
+	)
+}
+
+func TestCommonStatsDLines(t *testing.T) {
+	s := &StatsDSink{cfg: &StatsD{Prefix: "lb."}}
+	lines := s.statsdLines(testSinkTick())
+	require.Equal(t, "lb.sink_runner.rps:10.5|g", lines[0])
+	require.Equal(t, "lb.sink_runner.custom_queue_sum:3|g", lines[len(lines)-1])
+
+	s = &StatsDSink{cfg: &StatsD{Prefix: "lb.", DogStatsD: true}, tags: []string{"env:ci"}}
+	tick := testSinkTick()
+	tick.Node = "node-1"
+	require.Equal(t, "lb.rps:10.5|g|#runner:sink runner,step:1,node:node-1,env:ci", s.statsdLines(tick)[0])
+
+	lines = make([]string, 0)
+	for i := 0; i < 100; i++ {
+		lines = append(lines, strings.Repeat("a", 99))
+	}
+	packets := statsdPackets(lines)
+	require.Len(t, packets, 8)
+	for _, p := range packets {
+		require.LessOrEqual(t, len(p), statsdMaxPacket)
+	}
+}
+
+func TestCommonMetricSinks(t *testing.T) {
+	mu := &sync.Mutex{}
+	writes := make([][]byte, 0)
+	influx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		writes = append(writes, body)
+		mu.Unlock()
+		require.Equal(t, "Token secret", req.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer influx.Close()
+	statsd, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer statsd.Close()
+	packets := make(chan []byte, 100)
+	go func() {
+		buf := make([]byte, statsdMaxPacket)
+		for {
+			n, _, err := statsd.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			packets <- append([]byte{}, buf[:n]...)
+		}
+	}()
+
+	r := NewRunner(&RunnerConfig{
+		Name:            "sink_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		InfluxDB: &InfluxDB{URL: influx.URL, Token: "secret"},
+		StatsD:   &StatsD{Addr: statsd.LocalAddr().String(), DogStatsD: true},
+	}, &ControlAttackerMock{}, nil)
+	require.Len(t, r.Sinks, 2)
+	_, err = r.Run(context.TODO())
+	require.NoError(t, err)
+
+	mu.Lock()
+	require.GreaterOrEqual(t, len(writes), 2)
+	for _, w := range writes {
+		require.True(t, bytes.HasPrefix(w, []byte("loaderbot,runner=sink_runner,step=1 tick=")))
+	}
+	mu.Unlock()
+	select {
+	case p := <-packets:
+		require.True(t, bytes.HasPrefix(p, []byte("loaderbot.rps:")))
+		require.Contains(t, string(p), "|#runner:sink_runner,step:1")
+	case <-time.After(time.Second):
+		t.Fatal("no statsd packets received")
+	}
+}
@@ -32,6 +32,12 @@ type Metrics struct {
 	StatusCodes map[string]int `json:"status_codes"`
 	// Errors is a set of unique Errors returned by the targets during the attack.
 	Errors []string `json:"Errors"`
+	// HTTPPhases holds http phases latency metrics of requests made by provided clients.
+	HTTPPhases HTTPPhaseMetrics `json:"http_phases"`
+	// CustomMetrics is a set of aggregated user defined metrics emitted from Attack.Do.
+	CustomMetrics map[string]*CustomMetricSummary `json:"custom_metrics"`
+	// SLO holds Apdex and latency objectives compliance, nil if no SLO is set.
+	SLO *SLOMetrics `json:"slo,omitempty"`
 
 	errors      map[string]struct{}
 	errorsCount int64
@@ -104,6 +110,19 @@ func (m *Metrics) add(r AttackResult) {
 		m.Latencies.Max = r.Elapsed
 	}
 
+	m.HTTPPhases.add(r.HTTPTimings)
+
+	if m.SLO != nil {
+		m.SLO.add(r)
+	}
+
+	for _, cm := range r.DoResult.CustomMetrics {
+		if _, ok := m.CustomMetrics[cm.Name]; !ok {
+			m.CustomMetrics[cm.Name] = newCustomMetricSummary(cm.Name, cm.Type)
+		}
+		m.CustomMetrics[cm.Name].add(cm.Value)
+	}
+
 	if r.DoResult.Error != "" {
 		if _, ok := m.errors[r.DoResult.Error]; !ok {
 			m.errors[r.DoResult.Error] = struct{}{}
@@ -130,12 +149,20 @@ func (m *Metrics) update() {
 	m.Latencies.P50 = time.Duration(m.latencies.Get(0.50))
 	m.Latencies.P95 = time.Duration(m.latencies.Get(0.95))
 	m.Latencies.P99 = time.Duration(m.latencies.Get(0.99))
+	m.HTTPPhases.update()
+	if m.SLO != nil {
+		m.SLO.update()
+	}
+	for _, cm := range m.CustomMetrics {
+		cm.update()
+	}
 }
 
 func (m *Metrics) init() {
 	if m.latencies == nil {
 		m.StatusCodes = map[string]int{}
 		m.errors = map[string]struct{}{}
+		m.CustomMetrics = map[string]*CustomMetricSummary{}
 		m.latencies = quantile.New(
 			quantile.Known(0.50, 0.01),
 			quantile.Known(0.95, 0.001),
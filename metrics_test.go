@@ -0,0 +1,101 @@
+package loaderbot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonCustomMetricsAggregation(t *testing.T) {
+	m := NewMetrics()
+	for i := 1; i <= 100; i++ {
+		dr := DoResult{}
+		dr.Counter("items", 2)
+		dr.Gauge("queue_depth", float64(i))
+		dr.Timing("cache_lookup", time.Duration(i)*time.Millisecond)
+		m.add(AttackResult{
+			Begin:    time.Now(),
+			End:      time.Now(),
+			DoResult: dr,
+		})
+	}
+	m.update()
+	items := m.CustomMetrics["items"]
+	require.Equal(t, CustomCounter, items.Type)
+	require.Equal(t, uint64(100), items.Count)
+	require.Equal(t, float64(200), items.Sum)
+
+	depth := m.CustomMetrics["queue_depth"]
+	require.Equal(t, float64(1), depth.Min)
+	require.Equal(t, float64(100), depth.Max)
+	require.Equal(t, float64(100), depth.Last)
+	require.Equal(t, 50.5, depth.Mean)
+
+	lookup := m.CustomMetrics["cache_lookup"]
+	require.InDelta(t, 50, lookup.P50, 2)
+	require.InDelta(t, 99, lookup.P99, 2)
+}
+
+func TestCommonSLOMetrics(t *testing.T) {
+	cfg := &SLO{
+		Objectives: []LatencyObjective{{Target: 0.9, ThresholdMs: 50}},
+		ApdexTMs:   10,
+	}
+	report := NewSLOReport(cfg)
+	for step := 1; step <= 2; step++ {
+		m := NewMetrics()
+		m.SLO = NewSLOMetrics(cfg)
+		for i := 1; i <= 100; i++ {
+			dr := DoResult{}
+			elapsed := time.Duration(i) * time.Millisecond
+			// second step fails 5 requests
+			if step == 2 && i <= 5 {
+				dr.Error = "failed"
+				elapsed = time.Millisecond
+			}
+			m.add(AttackResult{Begin: time.Now(), End: time.Now(), Elapsed: elapsed, DoResult: dr})
+		}
+		m.update()
+		report.addTick(step, m.SLO)
+	}
+	step1 := report.Steps[1]
+	// 10 satisfied, 30 tolerating
+	require.Equal(t, 0.25, step1.Apdex)
+	require.Equal(t, 0.5, step1.Objectives[0].Compliance)
+	require.InDelta(t, 5, step1.Objectives[0].BudgetBurn, 1e-9)
+	require.False(t, step1.Met())
+	require.Equal(t, "90% < 50ms", step1.Objectives[0].Name)
+
+	step2 := report.Steps[2]
+	require.Equal(t, uint64(55), step2.Objectives[0].Bad)
+	require.Equal(t, 0.2, step2.Apdex)
+
+	run := report.Run
+	require.Equal(t, uint64(200), run.Requests)
+	require.Equal(t, uint64(105), run.Objectives[0].Bad)
+	require.InDelta(t, 5.25, run.Objectives[0].BudgetBurn, 1e-9)
+}
+
+func TestCommonHTTPPhasesUnknown(t *testing.T) {
+	m := NewMetrics()
+	for i := 1; i <= 10; i++ {
+		// fasthttp requests have total time only
+		m.add(AttackResult{HTTPTimings: &HTTPTimings{Requests: 1, Total: time.Duration(i) * time.Millisecond}})
+	}
+	m.update()
+	require.Equal(t, uint64(10), m.HTTPPhases.Requests)
+	require.Equal(t, uint64(0), m.HTTPPhases.Traced)
+	require.InDelta(t, int64(10*time.Millisecond), int64(m.HTTPPhases.Total.P99), float64(time.Millisecond))
+	require.Zero(t, m.HTTPPhases.TTFB)
+	require.Zero(t, m.HTTPPhases.DNS)
+
+	// traced requests feed phases, untraced ones only total
+	m.add(AttackResult{HTTPTimings: &HTTPTimings{Requests: 1, Traced: 1, TTFB: 50 * time.Millisecond, Total: 60 * time.Millisecond}})
+	m.add(AttackResult{HTTPTimings: &HTTPTimings{Requests: 2, Traced: 1, TTFB: 90 * time.Millisecond, Total: 99 * time.Millisecond}})
+	m.update()
+	require.Equal(t, uint64(13), m.HTTPPhases.Requests)
+	require.Equal(t, uint64(1), m.HTTPPhases.Traced)
+	require.InDelta(t, int64(50*time.Millisecond), int64(m.HTTPPhases.TTFB.P99), float64(time.Millisecond))
+	require.GreaterOrEqual(t, int64(m.HTTPPhases.Total.P99), int64(60*time.Millisecond))
+}
@@ -0,0 +1,358 @@
+package loaderbot
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// TraceparentHeader W3C trace context header injected into requests of sampled attacks
+	TraceparentHeader = "traceparent"
+	// otelMaxSpans spans buffered between exports, the rest are dropped
+	otelMaxSpans = 10000
+	otelScope    = "loaderbot"
+	// otlp span kind and status codes
+	otelSpanKindClient  = 3
+	otelStatusCodeOK    = 1
+	otelStatusCodeError = 2
+)
+
+type spanCtxKey struct{}
+
+// SpanContext W3C trace context of a sampled attack
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+func newSpanContext() SpanContext {
+	var sc SpanContext
+	_, _ = crand.Read(sc.TraceID[:])
+	_, _ = crand.Read(sc.SpanID[:])
+	return sc
+}
+
+// Traceparent header value, attack span is the parent of backend spans
+func (sc SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-01"
+}
+
+func withSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanCtxKey{}, sc)
+}
+
+// SpanContextFromCtx span context of attack context passed to Do, false if attack is not sampled
+func SpanContextFromCtx(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanCtxKey{}).(SpanContext)
+	return sc, ok
+}
+
+// otlp json types, see opentelemetry-proto json encoding
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+func otlpString(key, v string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &v}}
+}
+
+func otlpInt(key string, v int64) otlpKeyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Unit  string    `json:"unit,omitempty"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otelExporter exports tick metrics and sampled attack spans to collector with OTLP/HTTP json encoding,
+// data is batched and exported periodically and on close
+type otelExporter struct {
+	cfg      *OpenTelemetry
+	resource otlpResource
+	client   *http.Client
+	mu       *sync.Mutex
+	metrics  map[string]*otlpMetric
+	order    []string
+	spans    []otlpSpan
+	dropped  int
+	done     chan struct{}
+	exited   chan struct{}
+	L        *Logger
+}
+
+func newOTelExporter(cfg *RunnerConfig, l *Logger) *otelExporter {
+	resource := otlpResource{Attributes: []otlpKeyValue{
+		otlpString("service.name", otelScope),
+		otlpString("loaderbot.runner", cfg.Name),
+		otlpString("loaderbot.mode", cfg.SystemMode.String()),
+	}}
+	// series of cluster nodes are told apart by node
+	if cfg.ClusterOptions != nil && cfg.ClusterOptions.Node != "" {
+		resource.Attributes = append(resource.Attributes, otlpString("loaderbot.node", cfg.ClusterOptions.Node))
+	}
+	return &otelExporter{
+		cfg:      cfg.OpenTelemetry,
+		resource: resource,
+		client:   &http.Client{Timeout: time.Duration(cfg.OpenTelemetry.TimeoutSec) * time.Second},
+		mu:       &sync.Mutex{},
+		metrics:  make(map[string]*otlpMetric),
+		spans:    make([]otlpSpan, 0),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		L:        l,
+	}
+}
+
+// sample starts span for attack with configured probability
+func (e *otelExporter) sample(ctx context.Context) (context.Context, *SpanContext) {
+	if e.cfg.SpanSampleRatio <= 0 || rand.Float64() >= e.cfg.SpanSampleRatio {
+		return ctx, nil
+	}
+	sc := newSpanContext()
+	return withSpanContext(ctx, sc), &sc
+}
+
+// addSpan buffers span of finished attack
+func (e *otelExporter) addSpan(sc *SpanContext, res AttackResult) {
+	status := otlpStatus{Code: otelStatusCodeOK}
+	if res.DoResult.Error != "" {
+		status = otlpStatus{Code: otelStatusCodeError, Message: res.DoResult.Error}
+	}
+	attrs := []otlpKeyValue{
+		otlpString("loaderbot.label", res.DoResult.RequestLabel),
+		otlpInt("loaderbot.tick", int64(res.AttackToken.Tick)),
+		otlpInt("loaderbot.step", int64(res.AttackToken.Step)),
+	}
+	if res.DoResult.StatusCode != 0 {
+		attrs = append(attrs, otlpInt("http.status_code", int64(res.DoResult.StatusCode)))
+	}
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(sc.TraceID[:]),
+		SpanID:            hex.EncodeToString(sc.SpanID[:]),
+		Name:              "attack " + res.DoResult.RequestLabel,
+		Kind:              otelSpanKindClient,
+		StartTimeUnixNano: unixNano(res.Begin),
+		EndTimeUnixNano:   unixNano(res.End),
+		Attributes:        attrs,
+		Status:            status,
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= otelMaxSpans {
+		e.dropped++
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+// addTick buffers tick metrics as gauge data points
+func (e *otelExporter) addTick(token attackToken, attackers int, m *Metrics) {
+	now := time.Now()
+	attrs := []otlpKeyValue{otlpInt("loaderbot.step", int64(token.Step))}
+	points := []struct {
+		name  string
+		unit  string
+		value float64
+	}{
+		{"loaderbot.tick.rps", "1/s", m.Rate},
+		{"loaderbot.tick.target_rps", "1/s", m.TargetRate},
+		{"loaderbot.tick.requests", "1", float64(m.Requests)},
+		{"loaderbot.tick.failed", "1", float64(m.Requests - uint64(m.success))},
+		{"loaderbot.tick.success_ratio", "1", m.Success},
+		{"loaderbot.tick.p50", "ms", durationMs(m.Latencies.P50)},
+		{"loaderbot.tick.p95", "ms", durationMs(m.Latencies.P95)},
+		{"loaderbot.tick.p99", "ms", durationMs(m.Latencies.P99)},
+		{"loaderbot.tick.max", "ms", durationMs(m.Latencies.Max)},
+		{"loaderbot.attackers", "1", float64(attackers)},
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, p := range points {
+		metric, ok := e.metrics[p.name]
+		if !ok {
+			metric = &otlpMetric{Name: p.name, Unit: p.unit}
+			e.metrics[p.name] = metric
+			e.order = append(e.order, p.name)
+		}
+		metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, otlpNumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: unixNano(now),
+			AsDouble:     p.value,
+		})
+	}
+}
+
+func (e *otelExporter) start() {
+	go func() {
+		defer close(e.exited)
+		ticker := time.NewTicker(time.Duration(e.cfg.ExportIntervalSec) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.done:
+				return
+			case <-ticker.C:
+				e.export()
+			}
+		}
+	}()
+}
+
+// close stops periodic export and exports the rest
+func (e *otelExporter) close() {
+	close(e.done)
+	<-e.exited
+	e.export()
+}
+
+// export sends buffered metrics and spans, batch is dropped if collector is unavailable
+func (e *otelExporter) export() {
+	e.mu.Lock()
+	metrics := make([]otlpMetric, 0, len(e.order))
+	for _, name := range e.order {
+		metrics = append(metrics, *e.metrics[name])
+	}
+	spans := e.spans
+	dropped := e.dropped
+	e.metrics = make(map[string]*otlpMetric)
+	e.order = nil
+	e.spans = make([]otlpSpan, 0)
+	e.dropped = 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.L.Warnf("otel: %d spans dropped, buffer is full", dropped)
+	}
+	if len(metrics) > 0 {
+		req := otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{{
+			Resource:     e.resource,
+			ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: otelScope}, Metrics: metrics}},
+		}}}
+		if err := e.post("/v1/metrics", req); err != nil {
+			e.L.Errorf("otel: failed to export metrics: %s", err)
+		}
+	}
+	if len(spans) > 0 {
+		req := otlpTracesRequest{ResourceSpans: []otlpResourceSpans{{
+			Resource:   e.resource,
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otelScope}, Spans: spans}},
+		}}}
+		if err := e.post("/v1/traces", req); err != nil {
+			e.L.Errorf("otel: failed to export spans: %s", err)
+		}
+	}
+}
+
+func (e *otelExporter) post(path string, payload interface{}) error {
+	body, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(e.cfg.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded %d: %s", res.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
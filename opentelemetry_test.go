@@ -0,0 +1,115 @@
+package loaderbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonTraceparent(t *testing.T) {
+	sc := SpanContext{}
+	sc.TraceID[15] = 1
+	sc.SpanID[0] = 0xab
+	require.Equal(t, "00-00000000000000000000000000000001-ab00000000000000-01", sc.Traceparent())
+	_, ok := SpanContextFromCtx(context.Background())
+	require.False(t, ok)
+	got, ok := SpanContextFromCtx(withSpanContext(context.Background(), sc))
+	require.True(t, ok)
+	require.Equal(t, sc, got)
+}
+
+func TestCommonOpenTelemetryExport(t *testing.T) {
+	mu := &sync.Mutex{}
+	traceparents := make([]string, 0)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, req.Header.Get(TraceparentHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	metrics := make([]otlpMetricsRequest, 0)
+	traces := make([]otlpTracesRequest, 0)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		require.Equal(t, "secret", req.Header.Get("Authorization"))
+		dec := jsoniter.NewDecoder(req.Body)
+		switch req.URL.Path {
+		case "/v1/metrics":
+			var m otlpMetricsRequest
+			require.NoError(t, dec.Decode(&m))
+			metrics = append(metrics, m)
+		case "/v1/traces":
+			var tr otlpTracesRequest
+			require.NoError(t, dec.Decode(&tr))
+			traces = append(traces, tr)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	r := NewRunner(&RunnerConfig{
+		TargetUrl:       target.URL,
+		Name:            "otel_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		OpenTelemetry: &OpenTelemetry{
+			Endpoint:          collector.URL + "/",
+			Headers:           map[string]string{"Authorization": "secret"},
+			SpanSampleRatio:   1,
+			ExportIntervalSec: 1,
+		},
+	}, &HTTPAttackerExample{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, len(metrics), 2)
+	names := make(map[string]bool)
+	for _, m := range metrics {
+		rm := m.ResourceMetrics[0]
+		require.Contains(t, rm.Resource.Attributes, otlpString("loaderbot.runner", "otel_runner"))
+		for _, metric := range rm.ScopeMetrics[0].Metrics {
+			names[metric.Name] = true
+			require.NotEmpty(t, metric.Gauge.DataPoints)
+		}
+	}
+	require.True(t, names["loaderbot.tick.rps"])
+	require.True(t, names["loaderbot.tick.p99"])
+
+	spans := make(map[string]otlpSpan)
+	for _, tr := range traces {
+		for _, s := range tr.ResourceSpans[0].ScopeSpans[0].Spans {
+			spans[s.TraceID] = s
+		}
+	}
+	// attacks in flight on shutdown have no results, so their requests have no exported spans
+	require.NotEmpty(t, spans)
+	sent := make(map[string]bool)
+	for _, tp := range traceparents {
+		sent[tp] = true
+	}
+	for _, s := range spans {
+		require.True(t, sent["00-"+s.TraceID+"-"+s.SpanID+"-01"], "no request with span %s", s.SpanID)
+		require.Equal(t, otelSpanKindClient, s.Kind)
+		require.Equal(t, otelStatusCodeOK, s.Status.Code)
+	}
+}
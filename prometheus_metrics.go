@@ -1,20 +1,108 @@
 package loaderbot
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// promServer serves metrics of one runner on /metrics while it runs
+type promServer struct {
+	srv *http.Server
+	L   *Logger
+}
+
+func newPromServer(port int, m *PromReporter, l *Logger) *promServer {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.handler())
+	return &promServer{
+		srv: &http.Server{
+			Addr:    fmt.Sprintf(":%d", port),
+			Handler: mux,
+		},
+		L: l,
+	}
+}
+
+func (s *promServer) start() {
+	go func() {
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.L.Error(err)
+		}
+	}()
+}
+
+// close stops server, so next runner can serve its metrics on the same port
+func (s *promServer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.L.Error(err)
+	}
+}
+
 type PromReporter struct {
+	registry             *prometheus.Registry
+	promRequests         *prometheus.CounterVec
+	promLatency          *prometheus.HistogramVec
+	promAttackers        prometheus.Gauge
+	promRunSummary       *prometheus.GaugeVec
 	promTickSuccessRatio prometheus.Gauge
 	promTickP50          prometheus.Gauge
 	promTickP95          prometheus.Gauge
 	promTickP99          prometheus.Gauge
 	promTickMax          prometheus.Gauge
 	promRPS              prometheus.Gauge
+	promCustom           *prometheus.GaugeVec
+	promHTTPPhases       *prometheus.GaugeVec
+	promConnReuse        prometheus.Gauge
+	promGenerator        *prometheus.GaugeVec
+	promScheduler        *prometheus.GaugeVec
+	promInFlight         *prometheus.GaugeVec
+	promApdex            *prometheus.GaugeVec
+	promSLO              *prometheus.GaugeVec
 }
 
-func NewPromReporter(label string) *PromReporter {
-	m := &PromReporter{}
+// NewPromReporter creates runner metrics on its own registry, latency buckets are in seconds, prometheus.DefBuckets if empty
+func NewPromReporter(label string, buckets []float64) *PromReporter {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	m := &PromReporter{registry: prometheus.NewRegistry()}
+	m.promRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loaderbot_requests_total",
+		Help: "Requests by label, status code and error category",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"label", "status_code", "error_category"})
+	m.promLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "loaderbot_request_duration_seconds",
+		Help:    "Request latency by label",
+		Buckets: buckets,
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"label"})
+	m.promAttackers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "loaderbot_attackers",
+		Help: "Current amount of attackers",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	})
+	m.promRunSummary = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_run_summary",
+		Help: "Summary of finished run: requests, success ratio, max rps, latencies in seconds, passed",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"stat"})
 	m.promTickSuccessRatio = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_success_ratio",
 		Help: "Success requests ratio",
@@ -22,7 +110,6 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promTickSuccessRatio)
 	m.promTickP50 = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_p50",
 		Help: "Response time 50 Percentile",
@@ -30,7 +117,6 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promTickP50)
 	m.promTickP95 = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_p95",
 		Help: "Response time 95 Percentile",
@@ -38,7 +124,6 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promTickP95)
 	m.promTickP99 = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_p99",
 		Help: "Response time 99 Percentile",
@@ -46,7 +131,6 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promTickP99)
 	m.promTickMax = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_max",
 		Help: "Response time MAX",
@@ -54,7 +138,6 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promTickMax)
 	m.promRPS = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "loaderbot_tick_rps",
 		Help: "Requests per second rate",
@@ -62,10 +145,146 @@ func NewPromReporter(label string) *PromReporter {
 			"runner_name": label,
 		},
 	})
-	_ = prometheus.Register(m.promRPS)
+	m.promCustom = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_tick_custom",
+		Help: "Custom metrics emitted from attackers, aggregated per tick",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"name", "type", "stat"})
+	m.promHTTPPhases = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_tick_http_phase",
+		Help: "Http request phase time percentiles, ms",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"phase", "quantile"})
+	m.promConnReuse = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "loaderbot_tick_http_conn_reuse_ratio",
+		Help: "Ratio of http requests made on reused connection",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	})
+	m.promGenerator = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_generator",
+		Help: "Load generator process health",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"stat"})
+	m.promScheduler = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_tick_scheduler",
+		Help: "Schedule plan execution: target and achieved rate, missed slots, lag ms",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"stat"})
+	m.promInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_tick_in_flight",
+		Help: "In-flight attacks and idle attackers observed during tick",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"stat"})
+	m.promApdex = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_apdex",
+		Help: "Apdex score of current tick, step and the whole run",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"scope"})
+	m.promSLO = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loaderbot_slo",
+		Help: "Latency objectives compliance and error budget burn of current tick, step and the whole run",
+		ConstLabels: prometheus.Labels{
+			"runner_name": label,
+		},
+	}, []string{"scope", "objective", "stat"})
+	m.registry.MustRegister(
+		m.promRequests,
+		m.promLatency,
+		m.promAttackers,
+		m.promRunSummary,
+		m.promTickSuccessRatio,
+		m.promTickP50,
+		m.promTickP95,
+		m.promTickP99,
+		m.promTickMax,
+		m.promRPS,
+		m.promCustom,
+		m.promHTTPPhases,
+		m.promConnReuse,
+		m.promGenerator,
+		m.promScheduler,
+		m.promInFlight,
+		m.promApdex,
+		m.promSLO,
+	)
 	return m
 }
 
+// handler serves default registry with process and go metrics and runner registry
+func (m *PromReporter) handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, m.registry}, promhttp.HandlerOpts{})
+}
+
+// Registry runner metrics registry
+func (m *PromReporter) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// reportResult counts request and observes its latency
+func (m *PromReporter) reportResult(res AttackResult) {
+	m.promRequests.WithLabelValues(
+		res.DoResult.RequestLabel,
+		strconv.Itoa(res.DoResult.StatusCode),
+		promErrorCategory(res),
+	).Inc()
+	m.promLatency.WithLabelValues(res.DoResult.RequestLabel).Observe(res.Elapsed.Seconds())
+}
+
+// promErrorCategory dashboard error category, none for successful requests
+func promErrorCategory(res AttackResult) string {
+	if c := errorCategory(res); c != "" {
+		return c
+	}
+	return "none"
+}
+
+// reportSummary sets run summary, it's pushed on completion
+func (m *PromReporter) reportSummary(rm *RunManifest) {
+	s := rm.Summary
+	m.promRunSummary.WithLabelValues("requests").Set(float64(s.Requests))
+	m.promRunSummary.WithLabelValues("success_ratio").Set(s.Success)
+	m.promRunSummary.WithLabelValues("max_rps").Set(s.MaxRPS)
+	m.promRunSummary.WithLabelValues("p50_seconds").Set(s.Latencies.P50.Seconds())
+	m.promRunSummary.WithLabelValues("p95_seconds").Set(s.Latencies.P95.Seconds())
+	m.promRunSummary.WithLabelValues("p99_seconds").Set(s.Latencies.P99.Seconds())
+	m.promRunSummary.WithLabelValues("max_seconds").Set(s.Latencies.Max.Seconds())
+	m.promRunSummary.WithLabelValues("duration_seconds").Set(rm.End.Sub(rm.Start).Seconds())
+	var passed float64
+	if rm.Passed {
+		passed = 1
+	}
+	m.promRunSummary.WithLabelValues("passed").Set(passed)
+}
+
+func (m *PromReporter) reportAttackers(attackers int64) {
+	m.promAttackers.Set(float64(attackers))
+}
+
+// ReportTick sets tick gauges, PromReporter is a MetricSink scraped or pushed by prometheus
+func (m *PromReporter) ReportTick(t SinkTick) {
+	m.reportTick(&TickMetrics{Metrics: t.Metrics, Scheduler: t.Scheduler, InFlight: t.InFlight})
+	m.reportAttackers(int64(t.Attackers))
+}
+
+// Close nothing to flush, metrics are served or pushed by runner
+func (m *PromReporter) Close() error {
+	return nil
+}
+
 func (m *PromReporter) reportTick(tm *TickMetrics) {
 	m.promTickP50.Set(float64(tm.Metrics.Latencies.P50.Milliseconds()))
 	m.promTickP95.Set(float64(tm.Metrics.Latencies.P95.Milliseconds()))
@@ -73,4 +292,70 @@ func (m *PromReporter) reportTick(tm *TickMetrics) {
 	m.promTickMax.Set(float64(tm.Metrics.Latencies.Max.Milliseconds()))
 	m.promTickSuccessRatio.Set(tm.Metrics.Success)
 	m.promRPS.Set(tm.Metrics.Rate)
+	if s := tm.Scheduler; s != nil {
+		m.promScheduler.WithLabelValues("target_rate").Set(float64(s.TargetRPS))
+		m.promScheduler.WithLabelValues("achieved_rate").Set(s.AchievedRate)
+		m.promScheduler.WithLabelValues("missed_slots").Set(float64(s.Missed))
+		m.promScheduler.WithLabelValues("lag_mean_ms").Set(durationMs(s.LagMean))
+		m.promScheduler.WithLabelValues("lag_max_ms").Set(durationMs(s.LagMax))
+	}
+	if s := tm.InFlight; s != nil {
+		m.promInFlight.WithLabelValues("in_flight_min").Set(float64(s.InFlightMin))
+		m.promInFlight.WithLabelValues("in_flight_mean").Set(s.InFlightMean)
+		m.promInFlight.WithLabelValues("in_flight_max").Set(float64(s.InFlightMax))
+		m.promInFlight.WithLabelValues("idle_min").Set(float64(s.IdleMin))
+		m.promInFlight.WithLabelValues("idle_mean").Set(s.IdleMean)
+		m.promInFlight.WithLabelValues("idle_max").Set(float64(s.IdleMax))
+	}
+	if phases := tm.Metrics.HTTPPhases; phases.Requests > 0 {
+		for _, phase := range httpPhases {
+			if phase != "total" && phases.Traced == 0 {
+				continue
+			}
+			p := phases.byName(phase)
+			m.promHTTPPhases.WithLabelValues(phase, "0.5").Set(durationMs(p.P50))
+			m.promHTTPPhases.WithLabelValues(phase, "0.95").Set(durationMs(p.P95))
+			m.promHTTPPhases.WithLabelValues(phase, "0.99").Set(durationMs(p.P99))
+		}
+		m.promConnReuse.Set(phases.ConnReuse)
+	}
+	for _, cm := range tm.Metrics.CustomMetrics {
+		typ := cm.Type.String()
+		switch cm.Type {
+		case CustomCounter:
+			m.promCustom.WithLabelValues(cm.Name, typ, "sum").Set(cm.Sum)
+		case CustomGauge:
+			m.promCustom.WithLabelValues(cm.Name, typ, "last").Set(cm.Last)
+			m.promCustom.WithLabelValues(cm.Name, typ, "mean").Set(cm.Mean)
+			m.promCustom.WithLabelValues(cm.Name, typ, "max").Set(cm.Max)
+		case CustomTiming:
+			m.promCustom.WithLabelValues(cm.Name, typ, "p50").Set(cm.P50)
+			m.promCustom.WithLabelValues(cm.Name, typ, "p95").Set(cm.P95)
+			m.promCustom.WithLabelValues(cm.Name, typ, "p99").Set(cm.P99)
+		}
+	}
+}
+
+func (m *PromReporter) reportSLO(scope string, s *SLOMetrics) {
+	if s.apdexT > 0 {
+		m.promApdex.WithLabelValues(scope).Set(s.Apdex)
+	}
+	for _, o := range s.Objectives {
+		m.promSLO.WithLabelValues(scope, o.Name, "compliance").Set(o.Compliance)
+		m.promSLO.WithLabelValues(scope, o.Name, "budget_burn").Set(o.BudgetBurn)
+	}
+}
+
+func (m *PromReporter) reportGenerator(s GeneratorStats) {
+	m.promGenerator.WithLabelValues("cpu_percent").Set(s.CPUPercent)
+	m.promGenerator.WithLabelValues("heap_alloc_bytes").Set(float64(s.HeapAlloc))
+	m.promGenerator.WithLabelValues("gc_pause_max_ms").Set(durationMs(s.GCPauseMax))
+	m.promGenerator.WithLabelValues("goroutines").Set(float64(s.Goroutines))
+	m.promGenerator.WithLabelValues("open_fds").Set(float64(s.OpenFDs))
+	m.promGenerator.WithLabelValues("results_queue").Set(float64(s.ResultsQueue))
+	var saturated float64
+	if s.Saturated() {
+		saturated = 1
+	}
+	m.promGenerator.WithLabelValues("saturated").Set(saturated)
 }
@@ -0,0 +1,91 @@
+package loaderbot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonPromReporterRequests(t *testing.T) {
+	m := NewPromReporter("prom_runner", []float64{0.01, 0.1, 1})
+	m.reportResult(AttackResult{DoResult: DoResult{RequestLabel: "get", StatusCode: 200}, Elapsed: 5 * time.Millisecond})
+	m.reportResult(AttackResult{DoResult: DoResult{RequestLabel: "get", StatusCode: 200}, Elapsed: 50 * time.Millisecond})
+	m.reportResult(AttackResult{DoResult: DoResult{RequestLabel: "get", StatusCode: 503}, Elapsed: 50 * time.Millisecond})
+	m.reportResult(AttackResult{DoResult: DoResult{RequestLabel: "set", Error: errAttackDoTimedOut}, Elapsed: 2 * time.Second})
+	m.reportAttackers(10)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.promRequests.WithLabelValues("get", "200", "none")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.promRequests.WithLabelValues("get", "503", "status")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.promRequests.WithLabelValues("set", "0", "timeout")))
+	require.Equal(t, 10.0, testutil.ToFloat64(m.promAttackers))
+
+	families, err := m.Registry().Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, f := range families {
+		if f.GetName() != "loaderbot_request_duration_seconds" {
+			continue
+		}
+		found = true
+		for _, metric := range f.GetMetric() {
+			h := metric.GetHistogram()
+			require.Len(t, h.GetBucket(), 3)
+			if metric.GetLabel()[0].GetValue() == "get" {
+				require.Equal(t, uint64(3), h.GetSampleCount())
+				require.Equal(t, uint64(1), h.GetBucket()[0].GetCumulativeCount())
+			}
+		}
+	}
+	require.True(t, found)
+
+	// runner with the same name serves only its own registry
+	m2 := NewPromReporter("prom_runner", nil)
+	m2.reportResult(AttackResult{DoResult: DoResult{RequestLabel: "get", StatusCode: 200}})
+	srv := httptest.NewServer(m2.handler())
+	defer srv.Close()
+	res, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, 200, res.StatusCode)
+	require.Contains(t, string(body), `loaderbot_requests_total{error_category="none",label="get",runner_name="prom_runner",status_code="200"} 1`)
+	require.Contains(t, string(body), "go_goroutines")
+}
+
+func TestCommonPromServerPerRunner(t *testing.T) {
+	scrape := func() string {
+		res, err := http.Get("http://localhost:2113/metrics")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	for _, name := range []string{"prom_server_1", "prom_server_2"} {
+		m := NewPromReporter(name, nil)
+		m.reportAttackers(1)
+		s := newPromServer(2113, m, NewLogger(&RunnerConfig{LogLevel: "info", LogEncoding: "console"}))
+		s.start()
+		require.Eventually(t, func() bool {
+			_, err := http.Get("http://localhost:2113/metrics")
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+		body := scrape()
+		require.Contains(t, body, fmt.Sprintf(`runner_name="%s"`, name))
+		if name == "prom_server_2" {
+			// registry of finished runner is not served anymore
+			require.NotContains(t, body, `runner_name="prom_server_1"`)
+		}
+		s.close()
+		// port is released when run ends
+		_, err := http.Get("http://localhost:2113/metrics")
+		require.Error(t, err)
+	}
+}
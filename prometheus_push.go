@@ -0,0 +1,90 @@
+package loaderbot
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushTimeout timeout of one push to pushgateway
+const pushTimeout = 10 * time.Second
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// promPusher pushes runner registry to pushgateway periodically while run is active
+type promPusher struct {
+	pusher   *push.Pusher
+	interval time.Duration
+	done     chan struct{}
+	exited   chan struct{}
+	L        *Logger
+}
+
+// newPromPusher job is run name, grouping labels are metadata values of string, bool and number types
+func newPromPusher(cfg *RunnerConfig, m *PromReporter, l *Logger) *promPusher {
+	p := push.New(cfg.Prometheus.Pushgateway, cfg.Name).
+		Gatherer(m.Registry()).
+		Client(&http.Client{Timeout: pushTimeout})
+	for _, g := range pushGrouping(cfg.Metadata) {
+		p = p.Grouping(g[0], g[1])
+	}
+	return &promPusher{
+		pusher:   p,
+		interval: time.Duration(cfg.Prometheus.PushIntervalSec) * time.Second,
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		L:        l,
+	}
+}
+
+// pushGrouping sorted grouping labels from metadata, names are sanitized to be valid label names
+func pushGrouping(metadata map[string]interface{}) [][2]string {
+	res := make([][2]string, 0)
+	for k, v := range metadata {
+		switch v.(type) {
+		case string, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		default:
+			continue
+		}
+		name := invalidLabelChars.ReplaceAllString(k, "_")
+		if name == "" || name == "job" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		res = append(res, [2]string{name, fmt.Sprint(v)})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+func (p *promPusher) start() {
+	go func() {
+		defer close(p.exited)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.push()
+			}
+		}
+	}()
+}
+
+// stop stops periodic pushes, final push is made by caller after summary is reported
+func (p *promPusher) stop() {
+	close(p.done)
+	<-p.exited
+}
+
+// push replaces all metrics of the group with current registry state
+func (p *promPusher) push() {
+	if err := p.pusher.Push(); err != nil {
+		p.L.Errorf("failed to push metrics: %s", err)
+	}
+}
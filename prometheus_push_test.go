@@ -0,0 +1,76 @@
+package loaderbot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pushRequest struct {
+	method string
+	path   string
+	body   []byte
+}
+
+func TestCommonPushGrouping(t *testing.T) {
+	require.Equal(t, [][2]string{{"_1st", "a"}, {"_job", "j"}, {"build_id", "42"}, {"env", "ci"}}, pushGrouping(map[string]interface{}{
+		"env":      "ci",
+		"build id": 42,
+		"1st":      "a",
+		"job":      "j",
+		"data":     []int{1},
+	}))
+}
+
+func TestCommonPushgateway(t *testing.T) {
+	mu := &sync.Mutex{}
+	pushes := make([]pushRequest, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		pushes = append(pushes, pushRequest{method: req.Method, path: req.URL.Path, body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	r := NewRunner(&RunnerConfig{
+		Name:            "push_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		Metadata: map[string]interface{}{"env": "ci", "build id": 42},
+		Prometheus: &Prometheus{
+			Pushgateway:     srv.URL,
+			PushIntervalSec: 1,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	// periodic pushes and final one with summary
+	require.GreaterOrEqual(t, len(pushes), 3)
+	for _, p := range pushes {
+		require.Equal(t, http.MethodPut, p.method)
+		// grouping labels order in url is not stable
+		require.True(t, strings.HasPrefix(p.path, "/metrics/job/push_runner/"))
+		require.Contains(t, p.path, "/build_id/42")
+		require.Contains(t, p.path, "/env/ci")
+		require.True(t, bytes.Contains(p.body, []byte("loaderbot_requests_total")))
+	}
+	last := pushes[len(pushes)-1].body
+	require.True(t, bytes.Contains(last, []byte("loaderbot_run_summary")))
+	require.False(t, bytes.Contains(pushes[0].body, []byte("loaderbot_run_summary")))
+}
@@ -0,0 +1,251 @@
+package loaderbot
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-echarts/go-echarts/charts"
+)
+
+const (
+	// RegeneratedPercsFile percs csv rebuilt from raw results, name and window
+	RegeneratedPercsFile = "percs_%s_regenerated_%s.csv"
+	// RegeneratedGraphFile percs html rebuilt from raw results, name and window
+	RegeneratedGraphFile = "percs_%s_regenerated_%s.html"
+)
+
+// RegenerateOptions how to re-slice recorded raw results
+type RegenerateOptions struct {
+	// Name of regenerated report, used in chart title and file names
+	Name string
+	// Window aggregation window instead of a tick, default is 1s
+	Window time.Duration
+	// Labels request labels to keep, all labels are kept if empty
+	Labels []string
+	// From results which began earlier after run start are skipped
+	From time.Duration
+	// To results which began later after run start are skipped, no limit if 0
+	To time.Duration
+	// CSVDir percs csv directory, default is results_csv
+	CSVDir string
+	// HTMLDir html report directory, default is results_html, html is not written if NoHTML is set
+	HTMLDir string
+	NoHTML  bool
+}
+
+func (o *RegenerateOptions) defaults() {
+	if o.Name == "" {
+		o.Name = "report"
+	}
+	if o.Window == 0 {
+		o.Window = time.Second
+	}
+	if o.CSVDir == "" {
+		o.CSVDir = "results_csv"
+	}
+	if o.HTMLDir == "" {
+		o.HTMLDir = "results_html"
+	}
+}
+
+// RegeneratedReport files and stats of regenerated report
+type RegeneratedReport struct {
+	PercsFile string
+	HTMLFile  string
+	// Windows amount of aggregated windows written
+	Windows int
+	// Requests amount of results matching filters
+	Requests uint64
+	// Knee saturation analysis, nil if results have no steps or are filtered by labels
+	Knee *Knee
+}
+
+// legacyResultsCsvColumns requests csv columns before Step and TargetRPS were added
+const legacyResultsCsvColumns = 6
+
+// ReadRawResults streams results recorded in binary log or requests csv,
+// requests csv has no tick, old requests csv has no step and target rps, they are zero
+func ReadRawResults(path string, fn func(AttackResult) error) error {
+	if strings.HasSuffix(path, ".lbr.gz") {
+		return ReadResultsLog(path, fn)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	// skip csv header
+	_, _ = reader.Read()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) != len(ResultsCsvHeader) && len(record) != legacyResultsCsvColumns {
+			return errors.New("malformed csv")
+		}
+		begin, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		end, err := strconv.ParseInt(record[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		elapsed, err := time.ParseDuration(record[3])
+		if err != nil {
+			return err
+		}
+		// old logs have status code written as a rune, it's ignored
+		statusCode, _ := strconv.Atoi(record[4])
+		errorMsg := record[5]
+		if errorMsg == "ok" {
+			errorMsg = ""
+		}
+		var token attackToken
+		if len(record) == len(ResultsCsvHeader) {
+			if token.Step, err = strconv.Atoi(record[6]); err != nil {
+				return err
+			}
+			if token.TargetRPS, err = strconv.Atoi(record[7]); err != nil {
+				return err
+			}
+		}
+		if err := fn(AttackResult{
+			AttackToken: token,
+			Begin:       time.Unix(0, begin),
+			End:         time.Unix(0, end),
+			Elapsed:     elapsed,
+			DoResult: DoResult{
+				RequestLabel: record[0],
+				StatusCode:   statusCode,
+				Error:        errorMsg,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+type regeneratedWindow struct {
+	step      int
+	targetRPS int
+	metrics   *Metrics
+}
+
+// RegenerateReport rebuilds percs csv and html chart from recorded raw results with another window,
+// label filter or time range
+func RegenerateReport(rawResultsPath string, opts RegenerateOptions) (*RegeneratedReport, error) {
+	opts.defaults()
+	labels := make(map[string]bool)
+	for _, l := range opts.Labels {
+		labels[l] = true
+	}
+	// results are not ordered by begin time, run start is found first
+	var start time.Time
+	err := ReadRawResults(rawResultsPath, func(res AttackResult) error {
+		if start.IsZero() || res.Begin.Before(start) {
+			start = res.Begin
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() {
+		return nil, errors.New("no results, nothing to regenerate")
+	}
+	report := &RegeneratedReport{}
+	windows := make(map[int]*regeneratedWindow)
+	err = ReadRawResults(rawResultsPath, func(res AttackResult) error {
+		if len(labels) > 0 && !labels[res.DoResult.RequestLabel] {
+			return nil
+		}
+		offset := res.Begin.Sub(start)
+		if offset < opts.From || (opts.To > 0 && offset > opts.To) {
+			return nil
+		}
+		idx := int(offset / opts.Window)
+		w, ok := windows[idx]
+		if !ok {
+			w = &regeneratedWindow{metrics: NewMetrics()}
+			windows[idx] = w
+		}
+		// window may cross step boundary, the latest step is reported
+		if res.AttackToken.Step >= w.step {
+			w.step = res.AttackToken.Step
+			w.targetRPS = res.AttackToken.TargetRPS
+		}
+		w.metrics.add(res)
+		report.Requests++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return nil, errors.New("no results match filters, nothing to regenerate")
+	}
+	idxs := make([]int, 0, len(windows))
+	for idx := range windows {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+
+	_ = os.MkdirAll(opts.CSVDir, os.ModePerm)
+	report.PercsFile = path.Join(opts.CSVDir, fmt.Sprintf(RegeneratedPercsFile, opts.Name, opts.Window))
+	f := CreateFileOrReplace(report.PercsFile)
+	defer f.Close()
+	percs := csv.NewWriter(f)
+	_ = percs.Write(PercsCsvHeader)
+	label := "all"
+	if len(opts.Labels) > 0 {
+		label = strings.Join(opts.Labels, "|")
+	}
+	for _, idx := range idxs {
+		w := windows[idx]
+		w.metrics.update()
+		// rate is computed over the whole window, not between the first and the last result
+		w.metrics.Rate = float64(w.metrics.Requests) / opts.Window.Seconds()
+		w.metrics.TargetRate = float64(w.targetRPS)
+		_ = percs.Write(percentilesRecord(label, idx, w.step, w.metrics))
+	}
+	percs.Flush()
+	if err := percs.Error(); err != nil {
+		return nil, err
+	}
+	report.Windows = len(idxs)
+	// target rps is the whole run target, rps of filtered labels would look saturated
+	if windows[idxs[len(idxs)-1]].step > 0 && len(opts.Labels) == 0 {
+		run, err := LoadRunCSV(report.PercsFile)
+		if err != nil {
+			return nil, err
+		}
+		report.Knee = DetectKnee(run.Steps, DefaultKneeOptions)
+	}
+	if opts.NoHTML {
+		return report, nil
+	}
+	chart, err := PercsChartWithKnee(report.PercsFile, fmt.Sprintf("%s, window %s", opts.Name, opts.Window), report.Knee)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.MkdirAll(opts.HTMLDir, os.ModePerm)
+	report.HTMLFile = path.Join(opts.HTMLDir, fmt.Sprintf(RegeneratedGraphFile, opts.Name, opts.Window))
+	page := charts.NewPage()
+	page.Add(chart)
+	RenderEPage(page, report.HTMLFile)
+	return report, nil
+}
@@ -0,0 +1,70 @@
+package loaderbot
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonRegenerateReport(t *testing.T) {
+	_ = os.MkdirAll("test_csv", os.ModePerm)
+	logPath := path.Join("test_csv", "requests_regenerate.lbr.gz")
+	f := CreateFileOrReplace(logPath)
+	w, err := NewResultsWriter(f)
+	require.NoError(t, err)
+	start := time.Now()
+	// 10 seconds, 10 rps per label, second step doubles latency
+	for sec := 0; sec < 10; sec++ {
+		step := sec/5 + 1
+		for i := 0; i < 10; i++ {
+			for _, label := range []string{"get", "put"} {
+				begin := start.Add(time.Duration(sec)*time.Second + time.Duration(i)*100*time.Millisecond)
+				elapsed := time.Duration(step*10) * time.Millisecond
+				require.NoError(t, w.Write(AttackResult{
+					AttackToken: attackToken{TargetRPS: step * 10, Step: step, Tick: sec},
+					Begin:       begin,
+					End:         begin.Add(elapsed),
+					Elapsed:     elapsed,
+					DoResult:    DoResult{RequestLabel: label},
+				}))
+			}
+		}
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	report, err := RegenerateReport(logPath, RegenerateOptions{
+		Name:    "regenerate",
+		Window:  2 * time.Second,
+		Labels:  []string{"get"},
+		From:    2 * time.Second,
+		CSVDir:  "test_csv",
+		HTMLDir: "test_html",
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(80), report.Requests)
+	require.Equal(t, 4, report.Windows)
+	require.FileExists(t, report.HTMLFile)
+
+	run, err := LoadRunCSV(report.PercsFile)
+	require.NoError(t, err)
+	require.Len(t, run.Steps, 2)
+	// first window of step 1 is skipped, 4th second of step 1 is merged into step 2 window
+	require.Equal(t, 1, run.Steps[0].Ticks)
+	require.Equal(t, float64(10), run.Steps[0].RPS)
+	require.Equal(t, float64(10), run.Steps[0].P50)
+	require.Equal(t, 3, run.Steps[1].Ticks)
+	// target rps of filtered label is unknown
+	require.Nil(t, report.Knee)
+
+	report, err = RegenerateReport(logPath, RegenerateOptions{Name: "regenerate_all", CSVDir: "test_csv", NoHTML: true})
+	require.NoError(t, err)
+	require.Equal(t, uint64(200), report.Requests)
+	require.NotNil(t, report.Knee)
+
+	_, err = RegenerateReport(logPath, RegenerateOptions{Labels: []string{"delete"}, CSVDir: "test_csv", NoHTML: true})
+	require.Error(t, err)
+}
@@ -3,24 +3,46 @@ package loaderbot
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-echarts/go-echarts/charts"
 	"github.com/google/uuid"
 )
 
 type Report struct {
-	runId               string
-	runName             string
-	requestsLogFilename string
-	percsReportFilename string
-	percLogFilename     string
-	requestsLogFile     *csv.Writer
-	percLogFile         *csv.Writer
-	reportOptions       *ReportOptions
-	L                   *Logger
+	runId                string
+	runName              string
+	requestsLogFilename  string
+	rawResultsFilename   string
+	percsReportFilename  string
+	percLogFilename      string
+	customLogFilename    string
+	phasesLogFilename    string
+	generatorLogFilename string
+	schedulerLogFilename string
+	inFlightLogFilename  string
+	sloLogFilename       string
+	dashboardFilename    string
+	heatmapFilename      string
+	requestsLogFile      *csv.Writer
+	rawResultsFile       *os.File
+	rawResultsLog        *ResultsWriter
+	percLogFile          *csv.Writer
+	customLogFile        *csv.Writer
+	phasesLogFile        *csv.Writer
+	generatorLogFile     *csv.Writer
+	schedulerLogFile     *csv.Writer
+	inFlightLogFile      *csv.Writer
+	sloLogFile           *csv.Writer
+	dashboard            *Dashboard
+	reportOptions        *ReportOptions
+	L                    *Logger
 }
 
 func NewReport(cfg *RunnerConfig) *Report {
@@ -33,63 +55,419 @@ func NewReport(cfg *RunnerConfig) *Report {
 	requestsLogFilename := fmt.Sprintf(MetricsLogFile, cfg.Name, runId, tn)
 	requestsLogFilename = path.Join(cfg.ReportOptions.CSVDir, requestsLogFilename)
 
+	rawResultsFilename := fmt.Sprintf(RawResultsLogFile, cfg.Name, runId, tn)
+	rawResultsFilename = path.Join(cfg.ReportOptions.CSVDir, rawResultsFilename)
+
 	percLogFilename := fmt.Sprintf(PercsLogFile, cfg.Name, runId, tn)
 	percLogFilename = path.Join(cfg.ReportOptions.CSVDir, percLogFilename)
 
+	customLogFilename := fmt.Sprintf(CustomMetricsLogFile, cfg.Name, runId, tn)
+	customLogFilename = path.Join(cfg.ReportOptions.CSVDir, customLogFilename)
+
+	phasesLogFilename := fmt.Sprintf(HTTPPhasesLogFile, cfg.Name, runId, tn)
+	phasesLogFilename = path.Join(cfg.ReportOptions.CSVDir, phasesLogFilename)
+
+	generatorLogFilename := fmt.Sprintf(GeneratorLogFile, cfg.Name, runId, tn)
+	generatorLogFilename = path.Join(cfg.ReportOptions.CSVDir, generatorLogFilename)
+
+	schedulerLogFilename := fmt.Sprintf(SchedulerLogFile, cfg.Name, runId, tn)
+	schedulerLogFilename = path.Join(cfg.ReportOptions.CSVDir, schedulerLogFilename)
+
+	inFlightLogFilename := fmt.Sprintf(InFlightLogFile, cfg.Name, runId, tn)
+	inFlightLogFilename = path.Join(cfg.ReportOptions.CSVDir, inFlightLogFilename)
+
+	sloLogFilename := fmt.Sprintf(SLOLogFile, cfg.Name, runId, tn)
+	sloLogFilename = path.Join(cfg.ReportOptions.CSVDir, sloLogFilename)
+
+	dashboardFilename := fmt.Sprintf(DashboardFile, cfg.Name, runId, tn)
+	dashboardFilename = path.Join(cfg.ReportOptions.HTMLDir, dashboardFilename)
+
+	heatmapFilename := fmt.Sprintf(HeatmapPNGFile, cfg.Name, runId, tn)
+	heatmapFilename = path.Join(cfg.ReportOptions.HTMLDir, heatmapFilename)
+
 	percsReportFilename := fmt.Sprintf(ReportGraphFile, cfg.Name, runId, tn)
 	percsReportFilename = path.Join(cfg.ReportOptions.HTMLDir, percsReportFilename)
 
 	r := &Report{
-		runId:               runId,
-		runName:             cfg.Name,
-		requestsLogFilename: requestsLogFilename,
-		percsReportFilename: percsReportFilename,
-		percLogFilename:     percLogFilename,
-		requestsLogFile:     csv.NewWriter(CreateFileOrReplace(requestsLogFilename)),
-		percLogFile:         csv.NewWriter(CreateFileOrReplace(percLogFilename)),
-		reportOptions:       cfg.ReportOptions,
-		L:                   NewLogger(cfg).With("report", cfg.Name),
-	}
-	_ = r.requestsLogFile.Write(ResultsCsvHeader)
+		runId:                runId,
+		runName:              cfg.Name,
+		requestsLogFilename:  requestsLogFilename,
+		percsReportFilename:  percsReportFilename,
+		percLogFilename:      percLogFilename,
+		customLogFilename:    customLogFilename,
+		phasesLogFilename:    phasesLogFilename,
+		generatorLogFilename: generatorLogFilename,
+		schedulerLogFilename: schedulerLogFilename,
+		inFlightLogFilename:  inFlightLogFilename,
+		sloLogFilename:       sloLogFilename,
+		dashboardFilename:    dashboardFilename,
+		heatmapFilename:      heatmapFilename,
+		percLogFile:          csv.NewWriter(CreateFileOrReplace(percLogFilename)),
+		customLogFile:        csv.NewWriter(CreateFileOrReplace(customLogFilename)),
+		phasesLogFile:        csv.NewWriter(CreateFileOrReplace(phasesLogFilename)),
+		generatorLogFile:     csv.NewWriter(CreateFileOrReplace(generatorLogFilename)),
+		schedulerLogFile:     csv.NewWriter(CreateFileOrReplace(schedulerLogFilename)),
+		inFlightLogFile:      csv.NewWriter(CreateFileOrReplace(inFlightLogFilename)),
+		sloLogFile:           csv.NewWriter(CreateFileOrReplace(sloLogFilename)),
+		reportOptions:        cfg.ReportOptions,
+		L:                    NewLogger(cfg).With("report", cfg.Name),
+	}
+	if cfg.ReportOptions.RawResults != RawResultsBinary {
+		r.requestsLogFile = csv.NewWriter(CreateFileOrReplace(requestsLogFilename))
+		_ = r.requestsLogFile.Write(ResultsCsvHeader)
+	}
+	if cfg.ReportOptions.RawResults != RawResultsCSV {
+		r.rawResultsFilename = rawResultsFilename
+		r.rawResultsFile = CreateFileOrReplace(rawResultsFilename)
+		w, err := NewResultsWriter(r.rawResultsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r.rawResultsLog = w
+	}
 	_ = r.percLogFile.Write(PercsCsvHeader)
+	_ = r.customLogFile.Write(CustomMetricsCsvHeader)
+	_ = r.phasesLogFile.Write(HTTPPhasesCsvHeader)
+	_ = r.generatorLogFile.Write(GeneratorCsvHeader)
+	_ = r.schedulerLogFile.Write(SchedulerCsvHeader)
+	_ = r.inFlightLogFile.Write(InFlightCsvHeader)
+	_ = r.sloLogFile.Write(SLOCsvHeader)
+	// dashboard is a part of html reports, so it's written only with png reports
+	if cfg.ReportOptions.PNG {
+		r.dashboard = NewDashboard(cfg.Name)
+		r.dashboard.addSummary("Run id", runId)
+		r.dashboard.addSummary("Mode", cfg.SystemMode.String())
+	}
 	return r
 }
 
-func (r *Report) plot() {
+// artifacts paths of written reports by kind
+func (r *Report) artifacts() map[string]string {
+	res := map[string]string{
+		"percs":     r.percLogFilename,
+		"custom":    r.customLogFilename,
+		"phases":    r.phasesLogFilename,
+		"generator": r.generatorLogFilename,
+		"scheduler": r.schedulerLogFilename,
+		"inflight":  r.inFlightLogFilename,
+		"slo":       r.sloLogFilename,
+	}
+	if r.requestsLogFile != nil {
+		res["requests"] = r.requestsLogFilename
+	}
+	if r.rawResultsFilename != "" {
+		res["requests_binary"] = r.rawResultsFilename
+	}
+	if r.reportOptions.PNG {
+		res["html"] = r.percsReportFilename
+		res["dashboard"] = r.dashboardFilename
+		res["heatmap"] = r.heatmapFilename
+	}
+	return res
+}
+
+// detectKnee finds saturation knee of a stepped run in percs log
+func (r *Report) detectKnee() (*Knee, error) {
+	run, err := LoadRunCSV(r.percLogFilename)
+	if err != nil {
+		return nil, err
+	}
+	return DetectKnee(run.Steps, DefaultKneeOptions), nil
+}
+
+func (r *Report) plot(knee *Knee) {
 	if r.reportOptions.PNG {
 		r.L.Infof("reporting graphs: %s", r.percLogFilename)
-		chart, err := PercsChart(r.percLogFilename, r.runName)
+		chart, err := PercsChartWithKnee(r.percLogFilename, r.runName, knee)
 		if err != nil {
 			r.L.Error(err)
 			return
 		}
-		RenderEChart(chart, r.percsReportFilename)
+		// broken optional chart is skipped, the rest of the page is still rendered
+		customCharts, err := CustomMetricsCharts(r.customLogFilename)
+		if err != nil {
+			r.L.Error(err)
+		}
+		inFlightChart, err := InFlightChart(r.inFlightLogFilename)
+		if err != nil {
+			r.L.Error(err)
+		}
+		sloChart, err := SLOChart(r.sloLogFilename)
+		if err != nil {
+			r.L.Error(err)
+		}
+		page := charts.NewPage()
+		page.Add(chart)
+		if heatmap, err := HeatmapChart(r.dashboard.Heatmap, "Latency heatmap"); err != nil {
+			r.L.Error(err)
+		} else {
+			page.Add(heatmap)
+		}
+		if sloChart != nil {
+			page.Add(sloChart)
+		}
+		if inFlightChart != nil {
+			page.Add(inFlightChart)
+		}
+		for _, c := range customCharts {
+			page.Add(c)
+		}
+		RenderEPage(page, r.percsReportFilename)
+		if heatmap, err := HeatmapPNGChart(r.dashboard.Heatmap, r.runName); err == nil {
+			RenderChart(heatmap, r.heatmapFilename)
+		}
+	}
+}
+
+func (r *Report) writeDashboardTick(token attackToken, attackers int, tickMetrics *Metrics, samples []AttackResult) {
+	if r.dashboard == nil {
+		return
+	}
+	r.dashboard.addTick(token, attackers, tickMetrics, samples)
+}
+
+// writeDashboard renders dashboard with run metrics and outcomes added to summary
+func (r *Report) writeDashboard(run *Metrics, labels map[string]*Metrics, knee *Knee, slo *SLOReport, summary ...[2]string) {
+	if r.dashboard == nil {
+		return
+	}
+	r.dashboard.Run = run
+	r.dashboard.Labels = labels
+	if knee != nil {
+		r.dashboard.addSummary("Knee", knee.String())
+	}
+	if slo != nil {
+		r.dashboard.addSummary("SLO", slo.Run.String())
+	}
+	for _, s := range summary {
+		r.dashboard.addSummary(s[0], s[1])
+	}
+	r.L.Infof("reporting dashboard: %s", r.dashboardFilename)
+	if err := r.dashboard.WriteHTML(r.dashboardFilename); err != nil {
+		r.L.Error(err)
+	}
+}
+
+// writeMarkdownReport writes run summary, compared with baseline if it's set
+func (r *Report) writeMarkdownReport(m *RunManifest, path string) {
+	mr, err := r.newMarkdownReport(m, r.reportOptions.Baseline)
+	if err != nil {
+		r.L.Error(err)
+		return
+	}
+	if err := mr.WriteMarkdown(path); err != nil {
+		r.L.Error(err)
+		return
+	}
+	r.L.Infof("markdown report: %s", path)
+}
+
+// maxRPS max rps among ticks written to percs log
+func (r *Report) maxRPS() float64 {
+	f, err := os.Open(r.percLogFilename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	rpsSlice := make([]float64, 0)
+	csvFile := csv.NewReader(f)
+	for {
+		line, err := csvFile.Read()
+		if err == io.EOF {
+			break
+		}
+		rps, _ := strconv.ParseFloat(line[2], 64)
+		rpsSlice = append(rpsSlice, rps)
 	}
+	return MaxRPS(rpsSlice)
 }
 
 func (r *Report) flushLogs() {
 	r.percLogFile.Flush()
-	r.requestsLogFile.Flush()
+	if r.requestsLogFile != nil {
+		r.requestsLogFile.Flush()
+	}
+	// binary log is written once, so it's closed to finish compressed stream
+	if r.rawResultsLog != nil {
+		if err := r.rawResultsLog.Close(); err != nil {
+			r.L.Error(err)
+		}
+		_ = r.rawResultsFile.Close()
+		r.rawResultsLog = nil
+	}
+	r.customLogFile.Flush()
+	r.phasesLogFile.Flush()
+	r.generatorLogFile.Flush()
+	r.schedulerLogFile.Flush()
+	r.inFlightLogFile.Flush()
+	r.sloLogFile.Flush()
 }
 
 func (r *Report) writeResultEntry(res AttackResult, errorMsg string) {
+	if r.rawResultsLog != nil {
+		if err := r.rawResultsLog.Write(res); err != nil {
+			r.L.Error(err)
+		}
+	}
+	if r.requestsLogFile == nil {
+		return
+	}
 	_ = r.requestsLogFile.Write([]string{
 		res.DoResult.RequestLabel,
 		strconv.Itoa(int(res.Begin.UnixNano())),
 		strconv.Itoa(int(res.End.UnixNano())),
 		res.Elapsed.String(),
-		string(res.DoResult.StatusCode),
+		strconv.Itoa(res.DoResult.StatusCode),
 		errorMsg,
+		strconv.Itoa(res.AttackToken.Step),
+		strconv.Itoa(res.AttackToken.TargetRPS),
 	})
 }
 
 func (r *Report) writePercentilesEntry(res AttackResult, tickMetrics *Metrics) {
-	_ = r.percLogFile.Write([]string{
-		res.DoResult.RequestLabel,
-		strconv.Itoa(res.AttackToken.Tick),
+	_ = r.percLogFile.Write(percentilesRecord(res.DoResult.RequestLabel, res.AttackToken.Tick, res.AttackToken.Step, tickMetrics))
+}
+
+func percentilesRecord(label string, tick, step int, tickMetrics *Metrics) []string {
+	return []string{
+		label,
+		strconv.Itoa(tick),
 		strconv.Itoa(int(tickMetrics.Rate)),
 		strconv.Itoa(int(tickMetrics.Latencies.P50.Milliseconds())),
 		strconv.Itoa(int(tickMetrics.Latencies.P95.Milliseconds())),
 		strconv.Itoa(int(tickMetrics.Latencies.P99.Milliseconds())),
+		strconv.Itoa(step),
+		strconv.Itoa(int(tickMetrics.TargetRate)),
+		formatFloat(tickMetrics.Success),
+	}
+}
+
+func (r *Report) writeCustomMetricsEntry(res AttackResult, tickMetrics *Metrics) {
+	for _, cm := range sortedCustomMetrics(tickMetrics.CustomMetrics) {
+		_ = r.customLogFile.Write([]string{
+			res.DoResult.RequestLabel,
+			strconv.Itoa(res.AttackToken.Tick),
+			cm.Name,
+			cm.Type.String(),
+			strconv.FormatUint(cm.Count, 10),
+			formatFloat(cm.Sum),
+			formatFloat(cm.Min),
+			formatFloat(cm.Max),
+			formatFloat(cm.Mean),
+			formatFloat(cm.P50),
+			formatFloat(cm.P95),
+			formatFloat(cm.P99),
+		})
+	}
+}
+
+func (r *Report) writeHTTPPhasesEntry(res AttackResult, tickMetrics *Metrics) {
+	phases := tickMetrics.HTTPPhases
+	if phases.Requests == 0 {
+		return
+	}
+	record := []string{
+		res.DoResult.RequestLabel,
+		strconv.Itoa(res.AttackToken.Tick),
+		strconv.FormatUint(phases.Requests, 10),
+		formatFloat(phases.ConnReuse),
+	}
+	for _, phase := range httpPhases {
+		// phases are unknown if no request was traced, e.g. fasthttp
+		if phase != "total" && phases.Traced == 0 {
+			record = append(record, "", "", "")
+			continue
+		}
+		p := phases.byName(phase)
+		record = append(record, formatFloat(durationMs(p.P50)), formatFloat(durationMs(p.P95)), formatFloat(durationMs(p.P99)))
+	}
+	_ = r.phasesLogFile.Write(record)
+}
+
+func (r *Report) writeGeneratorEntry(s GeneratorStats) {
+	_ = r.generatorLogFile.Write([]string{
+		strconv.Itoa(s.Second),
+		formatFloat(s.CPUPercent),
+		strconv.FormatUint(s.HeapAlloc, 10),
+		strconv.FormatUint(s.Sys, 10),
+		strconv.FormatUint(uint64(s.NumGC), 10),
+		formatFloat(durationMs(s.GCPauseMax)),
+		strconv.Itoa(s.Goroutines),
+		strconv.Itoa(s.OpenFDs),
+		strconv.FormatUint(s.MaxFDs, 10),
+		strconv.Itoa(s.ResultsQueue),
+		strconv.Itoa(s.ResultsQueueCap),
+		strings.Join(s.Warnings, "; "),
+	})
+}
+
+func (r *Report) writeSchedulerEntry(s *SchedulerTickStats) {
+	if s == nil {
+		return
+	}
+	_ = r.schedulerLogFile.Write([]string{
+		strconv.Itoa(s.Tick),
+		strconv.Itoa(s.Step),
+		strconv.Itoa(s.TargetRPS),
+		strconv.Itoa(s.Fired),
+		strconv.Itoa(s.Missed),
+		formatFloat(s.AchievedRate),
+		formatFloat(durationMs(s.LagMean)),
+		formatFloat(durationMs(s.LagMax)),
+	})
+}
+
+func (r *Report) writeInFlightEntry(s *InFlightStats) {
+	if s == nil {
+		return
+	}
+	_ = r.inFlightLogFile.Write([]string{
+		strconv.Itoa(s.Tick),
+		strconv.FormatInt(s.InFlightMin, 10),
+		formatFloat(s.InFlightMean),
+		strconv.FormatInt(s.InFlightMax, 10),
+		strconv.FormatInt(s.IdleMin, 10),
+		formatFloat(s.IdleMean),
+		strconv.FormatInt(s.IdleMax, 10),
 	})
 }
+
+func (r *Report) writeSLOEntry(res AttackResult, s *SLOMetrics) {
+	if s == nil {
+		return
+	}
+	r.writeSLORows("tick", strconv.Itoa(res.AttackToken.Tick), strconv.Itoa(res.AttackToken.Step), s)
+}
+
+// writeSLOSummary writes SLO compliance of every step and the whole run
+func (r *Report) writeSLOSummary(s *SLOReport) {
+	if s == nil {
+		return
+	}
+	for _, step := range s.sortedSteps() {
+		r.writeSLORows("step", "", strconv.Itoa(step), s.Steps[step])
+	}
+	r.writeSLORows("run", "", "", s.Run)
+}
+
+func (r *Report) writeSLORows(scope, tick, step string, s *SLOMetrics) {
+	record := []string{scope, tick, step, strconv.FormatUint(s.Requests, 10), formatFloat(s.Apdex)}
+	if len(s.Objectives) == 0 {
+		_ = r.sloLogFile.Write(append(record, "", "", "", "", "", ""))
+		return
+	}
+	for _, o := range s.Objectives {
+		_ = r.sloLogFile.Write(append(record[:5:5],
+			o.Name,
+			strconv.FormatUint(o.Good, 10),
+			strconv.FormatUint(o.Bad, 10),
+			formatFloat(o.Compliance),
+			formatFloat(o.BudgetBurn),
+			strconv.FormatBool(o.Met),
+		))
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -10,6 +10,8 @@ type AttackResult struct {
 	Begin, End  time.Time
 	Elapsed     time.Duration
 	DoResult    DoResult
+	// HTTPTimings phase timings of http requests made by provided clients, nil if none were made
+	HTTPTimings *HTTPTimings
 }
 
 func (a AttackResult) String() string {
@@ -35,4 +37,6 @@ type DoResult struct {
 	BytesIn int64
 	// Number of bytes transferred when receiving the response.
 	BytesOut int64
+	// CustomMetrics user defined counters, gauges and timings, see Counter, Gauge and Timing
+	CustomMetrics []CustomMetric
 }
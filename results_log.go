@@ -0,0 +1,301 @@
+package loaderbot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	// RawResultsLogFile binary raw results log, see ResultsWriter
+	RawResultsLogFile = "requests_%s_%s_%d.lbr.gz"
+	// rawResultsMagic identifies raw results log, followed by format version
+	rawResultsMagic = "LBRL"
+	// rawResultsVersion 2 adds Traced to http timings
+	rawResultsVersion = 2
+	// maxRawResultSize protects reader from allocating garbage length
+	maxRawResultSize = 16 << 20
+)
+
+// RawResultsFormat raw results log formats
+type RawResultsFormat int
+
+const (
+	// RawResultsCSV writes requests csv
+	RawResultsCSV RawResultsFormat = iota
+	// RawResultsBinary writes compressed binary log only
+	RawResultsBinary
+	// RawResultsCSVAndBinary writes both
+	RawResultsCSVAndBinary
+)
+
+var errMalformedResultsLog = errors.New("malformed raw results log")
+
+// ResultsWriter writes gzip compressed stream of length prefixed binary encoded attack results
+type ResultsWriter struct {
+	gz  *gzip.Writer
+	buf []byte
+	rec []byte
+}
+
+// NewResultsWriter writes log header and returns writer, Close must be called to flush compressed stream
+func NewResultsWriter(w io.Writer) (*ResultsWriter, error) {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(append([]byte(rawResultsMagic), rawResultsVersion)); err != nil {
+		return nil, err
+	}
+	return &ResultsWriter{gz: gz, buf: make([]byte, binary.MaxVarintLen64)}, nil
+}
+
+// Write appends result to the log
+func (w *ResultsWriter) Write(res AttackResult) error {
+	rec := w.rec[:0]
+	rec = appendVarint(rec, int64(res.AttackToken.TargetRPS))
+	rec = appendVarint(rec, int64(res.AttackToken.Step))
+	rec = appendVarint(rec, int64(res.AttackToken.Tick))
+	rec = appendVarint(rec, res.Begin.UnixNano())
+	rec = appendVarint(rec, res.End.UnixNano())
+	rec = appendVarint(rec, int64(res.Elapsed))
+	rec = appendString(rec, res.DoResult.RequestLabel)
+	rec = appendString(rec, res.DoResult.Error)
+	rec = appendVarint(rec, int64(res.DoResult.StatusCode))
+	rec = appendVarint(rec, res.DoResult.BytesIn)
+	rec = appendVarint(rec, res.DoResult.BytesOut)
+	rec = appendVarint(rec, int64(len(res.DoResult.CustomMetrics)))
+	for _, cm := range res.DoResult.CustomMetrics {
+		rec = appendString(rec, cm.Name)
+		rec = append(rec, byte(cm.Type))
+		rec = appendFloat(rec, cm.Value)
+	}
+	if t := res.HTTPTimings; t != nil {
+		rec = append(rec, 1)
+		rec = appendVarint(rec, int64(t.Requests))
+		rec = appendVarint(rec, int64(t.Traced))
+		rec = appendVarint(rec, int64(t.ReusedConns))
+		rec = appendVarint(rec, int64(t.DNS))
+		rec = appendVarint(rec, int64(t.Connect))
+		rec = appendVarint(rec, int64(t.TLS))
+		rec = appendVarint(rec, int64(t.TTFB))
+		rec = appendVarint(rec, int64(t.Total))
+	} else {
+		rec = append(rec, 0)
+	}
+	w.rec = rec
+	n := binary.PutUvarint(w.buf, uint64(len(rec)))
+	if _, err := w.gz.Write(w.buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.gz.Write(rec)
+	return err
+}
+
+// Flush flushes compressed data written so far
+func (w *ResultsWriter) Flush() error {
+	return w.gz.Flush()
+}
+
+// Close flushes and finishes compressed stream, underlying writer is not closed
+func (w *ResultsWriter) Close() error {
+	return w.gz.Close()
+}
+
+// ResultsReader streams attack results from raw results log
+type ResultsReader struct {
+	gz  *gzip.Reader
+	r   *bufio.Reader
+	rec []byte
+}
+
+// NewResultsReader checks log header and returns reader
+func NewResultsReader(r io.Reader) (*ResultsReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(gz)
+	header := make([]byte, len(rawResultsMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, errMalformedResultsLog
+	}
+	if string(header[:len(rawResultsMagic)]) != rawResultsMagic {
+		return nil, errMalformedResultsLog
+	}
+	if header[len(rawResultsMagic)] != rawResultsVersion {
+		return nil, errors.New("unsupported raw results log version")
+	}
+	return &ResultsReader{gz: gz, r: br}, nil
+}
+
+// Read reads next result, returns io.EOF when log is over
+func (rr *ResultsReader) Read() (AttackResult, error) {
+	var res AttackResult
+	size, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		if err == io.EOF {
+			return res, io.EOF
+		}
+		return res, errMalformedResultsLog
+	}
+	if size > maxRawResultSize {
+		return res, errMalformedResultsLog
+	}
+	if uint64(cap(rr.rec)) < size {
+		rr.rec = make([]byte, size)
+	}
+	rec := rr.rec[:size]
+	if _, err := io.ReadFull(rr.r, rec); err != nil {
+		return res, errMalformedResultsLog
+	}
+	d := &recordDecoder{b: rec}
+	res.AttackToken.TargetRPS = int(d.varint())
+	res.AttackToken.Step = int(d.varint())
+	res.AttackToken.Tick = int(d.varint())
+	res.Begin = time.Unix(0, d.varint())
+	res.End = time.Unix(0, d.varint())
+	res.Elapsed = time.Duration(d.varint())
+	res.DoResult.RequestLabel = d.string()
+	res.DoResult.Error = d.string()
+	res.DoResult.StatusCode = int(d.varint())
+	res.DoResult.BytesIn = d.varint()
+	res.DoResult.BytesOut = d.varint()
+	if n := d.varint(); n > 0 && n <= int64(len(rec)) {
+		res.DoResult.CustomMetrics = make([]CustomMetric, n)
+		for i := range res.DoResult.CustomMetrics {
+			res.DoResult.CustomMetrics[i] = CustomMetric{
+				Name:  d.string(),
+				Type:  CustomMetricType(d.byte()),
+				Value: d.float(),
+			}
+		}
+	}
+	if d.byte() == 1 {
+		t := &HTTPTimings{Requests: int(d.varint())}
+		t.Traced = int(d.varint())
+		t.ReusedConns = int(d.varint())
+		t.DNS = time.Duration(d.varint())
+		t.Connect = time.Duration(d.varint())
+		t.TLS = time.Duration(d.varint())
+		t.TTFB = time.Duration(d.varint())
+		t.Total = time.Duration(d.varint())
+		res.HTTPTimings = t
+	}
+	if d.err != nil {
+		return AttackResult{}, d.err
+	}
+	return res, nil
+}
+
+// Close closes decompressor, underlying reader is not closed
+func (rr *ResultsReader) Close() error {
+	return rr.gz.Close()
+}
+
+// ReadResultsLog streams all results of raw results log file to fn, stops on first fn error
+func ReadResultsLog(path string, fn func(AttackResult) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rr, err := NewResultsReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	defer rr.Close()
+	for {
+		res, err := rr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(res); err != nil {
+			return err
+		}
+	}
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendVarint(b, int64(len(s)))
+	return append(b, s...)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	return append(b, buf[:]...)
+}
+
+// recordDecoder decodes record fields, first error is kept and the rest of fields are zero
+type recordDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *recordDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errMalformedResultsLog
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *recordDecoder) string() string {
+	n := d.varint()
+	if d.err != nil {
+		return ""
+	}
+	if n < 0 || n > int64(len(d.b)) {
+		d.err = errMalformedResultsLog
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *recordDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) < 1 {
+		d.err = errMalformedResultsLog
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *recordDecoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) < 8 {
+		d.err = errMalformedResultsLog
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+	d.b = d.b[8:]
+	return v
+}
@@ -0,0 +1,99 @@
+package loaderbot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonResultsLogRoundTrip(t *testing.T) {
+	begin := time.Now()
+	dr := DoResult{RequestLabel: "get_item", StatusCode: 200, BytesIn: 10, BytesOut: 2048}
+	dr.Counter("items", 3)
+	dr.Timing("cache_lookup", 2*time.Millisecond)
+	results := []AttackResult{
+		{
+			AttackToken: attackToken{TargetRPS: 100, Step: 2, Tick: 15},
+			Begin:       begin,
+			End:         begin.Add(30 * time.Millisecond),
+			Elapsed:     30 * time.Millisecond,
+			DoResult:    dr,
+			HTTPTimings: &HTTPTimings{Requests: 1, Traced: 1, ReusedConns: 1, TTFB: 25 * time.Millisecond, Total: 29 * time.Millisecond},
+		},
+		{
+			AttackToken: attackToken{TargetRPS: 100, Step: 2, Tick: 15},
+			Begin:       begin,
+			End:         begin.Add(time.Second),
+			Elapsed:     time.Second,
+			DoResult:    DoResult{RequestLabel: "get_item", Error: "timeout", StatusCode: 504},
+		},
+	}
+	var buf bytes.Buffer
+	w, err := NewResultsWriter(&buf)
+	require.NoError(t, err)
+	for _, res := range results {
+		require.NoError(t, w.Write(res))
+	}
+	require.NoError(t, w.Close())
+
+	rr, err := NewResultsReader(&buf)
+	require.NoError(t, err)
+	for _, expected := range results {
+		res, err := rr.Read()
+		require.NoError(t, err)
+		require.True(t, expected.Begin.Equal(res.Begin))
+		require.True(t, expected.End.Equal(res.End))
+		expected.Begin, expected.End = res.Begin, res.End
+		require.Equal(t, expected, res)
+	}
+	_, err = rr.Read()
+	require.Equal(t, io.EOF, err)
+
+	_, err = NewResultsReader(bytes.NewReader([]byte("not a log")))
+	require.Error(t, err)
+
+	// logs of older version have different http timings layout
+	buf.Reset()
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write(append([]byte(rawResultsMagic), rawResultsVersion-1))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	_, err = NewResultsReader(&buf)
+	require.EqualError(t, err, "unsupported raw results log version")
+}
+
+func TestCommonResultsLogRunner(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "results_log_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSVDir:     "test_csv",
+			CSV:        true,
+			RawResults: RawResultsCSVAndBinary,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	artifacts := r.Report.artifacts()
+	var binaryResults uint64
+	err = ReadResultsLog(artifacts["requests_binary"], func(res AttackResult) error {
+		binaryResults++
+		require.Equal(t, "results_log_runner", res.DoResult.RequestLabel)
+		return nil
+	})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, binaryResults, r.RunMetrics.Requests)
+	require.FileExists(t, artifacts["requests"])
+	latencies, err := LoadLatencies(artifacts["requests_binary"])
+	require.NoError(t, err)
+	require.Len(t, latencies, int(binaryResults))
+}
@@ -2,20 +2,16 @@ package loaderbot
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/gops/agent"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/google/uuid"
 	"go.uber.org/ratelimit"
 )
 
@@ -23,15 +19,45 @@ const (
 	DefaultResultsQueueCapacity = 100_000
 	MetricsLogFile              = "requests_%s_%s_%d.csv"
 	PercsLogFile                = "percs_%s_%s_%d.csv"
+	CustomMetricsLogFile        = "custom_%s_%s_%d.csv"
+	HTTPPhasesLogFile           = "phases_%s_%s_%d.csv"
+	GeneratorLogFile            = "generator_%s_%s_%d.csv"
+	SchedulerLogFile            = "scheduler_%s_%s_%d.csv"
+	InFlightLogFile             = "inflight_%s_%s_%d.csv"
+	SLOLogFile                  = "slo_%s_%s_%d.csv"
 	ReportGraphFile             = "percs_%s_%s_%d.html"
 	BoundRPSTickTemplate        = "step: %d, tick: %d, attackers: [%d], rate [%.4f -> %v], perc: 50 [%v] 95 [%v] 99 [%v], # requests [%d], %% success [%.4f]"
 	UnboundRPSTickTemplate      = "attackers: [%d], rate [%.4f], perc: 50 [%v] 95 [%v] 99 [%v], # requests [%d], %% success [%.4f]"
+	SchedulerTickTemplate       = "scheduler: achieved rate [%.4f -> %d], missed slots [%d], lag mean [%v] max [%v]"
+	InFlightTickTemplate        = "in flight: min [%d] mean [%.2f] max [%d], idle attackers: min [%d] mean [%.2f] max [%d]"
+	GeneratorTemplate           = "generator: cpu [%.2f%%], heap [%dMb], gc pause [%v], goroutines [%d], fds [%d], results queue [%d]"
+	HTTPPhasesTickTemplate      = "http phases: # requests [%d], reused conns [%.4f], perc 99: dns [%v] connect [%v] tls [%v] ttfb [%v] total [%v]"
+	HTTPTotalTickTemplate       = "http: # requests [%d], reused conns [%.4f], perc 99: total [%v]"
+	SLOTickTemplate             = "slo: tick: %s; step %d: %s"
 )
 
 var (
-	promOnce         = &sync.Once{}
-	ResultsCsvHeader = []string{"RequestLabel", "BeginTimeNano", "EndTimeNano", "Elapsed", "StatusCode", "Error"}
-	PercsCsvHeader   = []string{"RequestLabel", "Tick", "RPS", "P50", "P95", "P99"}
+	// ResultsCsvHeader requests csv header, logs written before Step and TargetRPS were added have first 6 columns
+	ResultsCsvHeader = []string{"RequestLabel", "BeginTimeNano", "EndTimeNano", "Elapsed", "StatusCode", "Error", "Step", "TargetRPS"}
+	PercsCsvHeader   = []string{"RequestLabel", "Tick", "RPS", "P50", "P95", "P99", "Step", "TargetRPS", "Success"}
+	// CustomMetricsCsvHeader custom metrics are written one row per metric name per tick
+	CustomMetricsCsvHeader = []string{"RequestLabel", "Tick", "Name", "Type", "Count", "Sum", "Min", "Max", "Mean", "P50", "P95", "P99"}
+	HTTPPhasesCsvHeader    = []string{
+		"RequestLabel", "Tick", "Requests", "ConnReuse",
+		"DNSP50", "DNSP95", "DNSP99",
+		"ConnectP50", "ConnectP95", "ConnectP99",
+		"TLSP50", "TLSP95", "TLSP99",
+		"TTFBP50", "TTFBP95", "TTFBP99",
+		"TotalP50", "TotalP95", "TotalP99",
+	}
+	SchedulerCsvHeader = []string{"Tick", "Step", "TargetRPS", "Fired", "Missed", "AchievedRate", "LagMean", "LagMax"}
+	InFlightCsvHeader  = []string{"Tick", "InFlightMin", "InFlightMean", "InFlightMax", "IdleMin", "IdleMean", "IdleMax"}
+	// SLOCsvHeader one row per objective per tick, step and step summary rows are written after the run with empty tick
+	SLOCsvHeader       = []string{"Scope", "Tick", "Step", "Requests", "Apdex", "Objective", "Good", "Bad", "Compliance", "BudgetBurn", "Met"}
+	GeneratorCsvHeader = []string{
+		"Second", "CPUPercent", "HeapAlloc", "Sys", "NumGC", "GCPauseMax",
+		"Goroutines", "OpenFDs", "MaxFDs", "ResultsQueue", "ResultsQueueCap", "Warnings",
+	}
 )
 
 // Controlled struct for adding test vars
@@ -48,6 +74,8 @@ type TestData struct {
 
 type ClusterTickMetrics struct {
 	Samples [][]AttackResult
+	// Nodes address of node of every samples batch
+	Nodes   []string
 	Metrics *Metrics
 }
 
@@ -55,6 +83,10 @@ type TickMetrics struct {
 	Samples  []AttackResult
 	Metrics  *Metrics
 	Reported bool
+	// Scheduler schedule plan execution stats, nil in UnboundRPS mode
+	Scheduler *SchedulerTickStats
+	// InFlight concurrency observed while tick was scheduled
+	InFlight *InFlightStats
 }
 
 type attackToken struct {
@@ -80,6 +112,19 @@ type Runner struct {
 	// metrics for every received tick (completed requests)
 	receivedTickMetricsMu *sync.Mutex
 	receivedTickMetrics   map[int]*TickMetrics
+	// tick currently scheduled, used to aggregate in-flight samples
+	scheduledTick int64
+	// in-flight stats for every scheduled tick, reported with tick metrics
+	inFlightStatsMu *sync.Mutex
+	inFlightStats   map[int]*InFlightStats
+	// Do calls in progress
+	inFlight int64
+	// attackers processing attack, the rest are idle
+	busyAttackers  int64
+	attackersCount int64
+	// scheduler stats for every completed tick, reported with tick metrics
+	schedulerTickStatsMu *sync.Mutex
+	schedulerTickStats   map[int]*SchedulerTickStats
 	// ratelimiter for keeping constant rps inside test step
 	rl ratelimit.Limiter
 	// TimeoutCtx test timeout ctx
@@ -99,8 +144,31 @@ type Runner struct {
 	uniqErrors map[string]int
 	// Failed means there some errors in test
 	Failed int64
+	// attacks are not scheduled while paused
+	paused int32
+	// MissedSlots amount of rate limiter slots skipped because no attacker was ready
+	MissedSlots int64
+	// SaturatedSamples amount of generator samples in which load generator itself was a bottleneck
+	SaturatedSamples int64
 	// Report data
 	Report *Report
+	// SLO compliance of a run and its steps, nil if no SLO is set
+	SLO *SLOReport
+	// Knee saturation analysis of a stepped run, available after run if csv report is enabled
+	Knee *Knee
+	// RunMetrics metrics aggregated over all reported ticks
+	RunMetrics *Metrics
+	// LabelMetrics metrics aggregated over all reported ticks by request label
+	LabelMetrics map[string]*Metrics
+	// labelSteps per step metrics by request label
+	labelSteps labelSteps
+	// min success ratio among reported ticks
+	minTickSuccess float64
+	ticksReported  int
+	// failureReason why runner was stopped before timeout
+	failureReason string
+	startTime     time.Time
+	endTime       time.Time
 	// data used to control attackers in test
 	controlled Controlled
 	// TestData data shared between attackers during test
@@ -108,6 +176,13 @@ type Runner struct {
 	HTTPClient     *http.Client
 	FastHTTPClient *FastHTTPClient
 	PromReporter   *PromReporter
+	Sinks          []MetricSink
+	promServer     *promServer
+	pusher         *promPusher
+	otel           *otelExporter
+	es             *ElasticsearchSink
+	live           *liveServer
+	tui            *terminalUI
 	wg             *sync.WaitGroup
 	L              *Logger
 }
@@ -132,7 +207,14 @@ func NewRunner(cfg *RunnerConfig, a Attack, data interface{}) *Runner {
 		OutResults:            make(chan []AttackResult, DefaultResultsQueueCapacity),
 		receivedTickMetricsMu: &sync.Mutex{},
 		receivedTickMetrics:   make(map[int]*TickMetrics),
+		schedulerTickStatsMu:  &sync.Mutex{},
+		inFlightStatsMu:       &sync.Mutex{},
+		inFlightStats:         make(map[int]*InFlightStats),
+		schedulerTickStats:    make(map[int]*SchedulerTickStats),
 		uniqErrors:            make(map[string]int),
+		RunMetrics:            NewMetrics(),
+		LabelMetrics:          make(map[string]*Metrics),
+		labelSteps:            make(labelSteps),
 		controlled:            Controlled{},
 		TestData:              data,
 		HTTPClient:            NewLoggingHTTPClient(cfg.DumpTransport, cfg.AttackerTimeout),
@@ -147,19 +229,36 @@ func NewRunner(cfg *RunnerConfig, a Attack, data interface{}) *Runner {
 		}
 		r.attackers = append(r.attackers, a)
 	}
+	r.attackersCount = int64(len(r.attackers))
+	r.SLO = NewSLOReport(cfg.SLO)
 	if cfg.ReportOptions.CSV {
 		r.Report = NewReport(r.Cfg)
 	}
+	if cfg.Prometheus != nil && (cfg.Prometheus.Enable || cfg.Prometheus.Pushgateway != "") {
+		r.PromReporter = NewPromReporter(r.Name, cfg.Prometheus.Buckets)
+	}
 	if cfg.Prometheus != nil && cfg.Prometheus.Enable {
-		r.PromReporter = NewPromReporter(r.Name)
-		promOnce.Do(func() {
-			go func() {
-				http.Handle("/metrics", promhttp.Handler())
-				if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.Prometheus.Port), nil); err != nil {
-					r.L.Error(err)
-				}
-			}()
-		})
+		r.promServer = newPromServer(cfg.Prometheus.Port, r.PromReporter, r.L)
+	}
+	if cfg.Prometheus != nil && cfg.Prometheus.Pushgateway != "" {
+		r.pusher = newPromPusher(r.Cfg, r.PromReporter, r.L)
+	}
+	if cfg.OpenTelemetry != nil {
+		r.otel = newOTelExporter(r.Cfg, r.L)
+	}
+	if r.PromReporter != nil {
+		r.Sinks = append(r.Sinks, r.PromReporter)
+	}
+	r.Sinks = append(r.Sinks, newMetricSinks(r.Cfg, r.L)...)
+	if cfg.Elasticsearch != nil {
+		runID := uuid.New().String()
+		if r.Report != nil {
+			runID = r.Report.runId
+		}
+		r.es = NewElasticsearchSink(cfg.Elasticsearch, runID, r.Name, r.L)
+	}
+	if cfg.LiveDashboard != nil && cfg.LiveDashboard.Enable {
+		r.live = newLiveServer(r.Name, cfg.LiveDashboard.Port, r.L)
 	}
 	return r
 }
@@ -171,33 +270,96 @@ func (r *Runner) Run(serverCtx context.Context) (float64, error) {
 	})
 
 	runStartTime := time.Now()
+	r.startTime = runStartTime
 	if r.Cfg.WaitBeforeSec > 0 {
 		r.L.Infof("waiting for %d seconds before start", r.Cfg.WaitBeforeSec)
 		time.Sleep(time.Duration(r.Cfg.WaitBeforeSec) * time.Second)
 	}
 	r.L.Infof("runner started, mode: %s", r.Cfg.SystemMode.String())
+	if r.live != nil {
+		r.live.start()
+	}
+	if r.promServer != nil {
+		r.promServer.start()
+	}
+	if r.pusher != nil {
+		r.pusher.start()
+	}
+	if r.otel != nil {
+		r.otel.start()
+	}
 	if serverCtx == nil {
 		serverCtx = context.Background()
 	}
 	r.TimeoutCtx, r.CancelFunc = context.WithTimeout(serverCtx, time.Duration(r.Cfg.TestTimeSec)*time.Second)
+	stopTUI := r.startTUI()
 	for atkIdx, attacker := range r.attackers {
 		r.L.Debugf("starting attacker: %d", atkIdx)
 		go attack(attacker, r)
 	}
 	r.handleShutdownSignal()
+	r.monitorGenerator()
+	r.trackInFlight()
 	r.schedule()
 	r.collectResults()
 	<-r.TimeoutCtx.Done()
 	r.wg.Wait()
+	stopTUI()
 	r.L.Infof("shutting down")
-	r.L.Infof("total run time: %.2f sec", time.Since(runStartTime).Seconds())
+	r.endTime = time.Now()
+	r.L.Infof("total run time: %.2f sec", r.endTime.Sub(runStartTime).Seconds())
+	if !r.Trustworthy() {
+		r.L.Warnf("load generator was saturated in %d samples, results are untrustworthy", atomic.LoadInt64(&r.SaturatedSamples))
+	}
+	r.reportSLO()
 	var maxRPS float64
 	if r.Cfg.ReportOptions.CSV {
+		r.Report.writeSLOSummary(r.SLO)
 		r.Report.flushLogs()
-		r.Report.plot()
+		r.reportKnee()
+		r.Report.plot(r.Knee)
+		r.Report.writeDashboard(r.RunMetrics, r.LabelMetrics, r.Knee, r.SLO,
+			[2]string{"Trustworthy", strconv.FormatBool(r.Trustworthy())},
+			[2]string{"Missed slots", strconv.FormatInt(atomic.LoadInt64(&r.MissedSlots), 10)},
+			[2]string{"Failure reason", r.failureReason},
+		)
 		maxRPS = r.maxRPS()
 		r.L.Infof("max rps: %.2f", maxRPS)
 	}
+	if r.Cfg.ReportOptions.JUnit {
+		writeJUnit(r.JUnit(), r.JUnitPath(), r.L)
+	}
+	manifest := r.Manifest(maxRPS)
+	// cluster nodes stream results, manifest of the whole run is written by cluster client
+	if !r.Cfg.ReportOptions.Stream {
+		writeManifest(manifest, r.ManifestPath(), r.L)
+	}
+	if r.Cfg.ReportOptions.CSV && r.Cfg.ReportOptions.Markdown {
+		r.Report.writeMarkdownReport(manifest, r.MarkdownReportPath())
+	}
+	if r.Cfg.Slack != nil {
+		postSlack(r.Cfg.Slack, r.Report, manifest, r.L)
+	}
+	if r.pusher != nil {
+		r.pusher.stop()
+		r.PromReporter.reportSummary(manifest)
+		r.pusher.push()
+	}
+	if r.otel != nil {
+		r.otel.close()
+	}
+	closeSinks(r.Sinks, r.L)
+	if r.es != nil {
+		if err := r.es.Close(); err != nil {
+			r.L.Warn(err)
+		}
+	}
+	if r.live != nil {
+		r.live.close()
+	}
+	if r.promServer != nil {
+		r.promServer.close()
+	}
 	r.safeCloseIdleConnections()
 	r.L.Infof("runner exited")
 	return maxRPS, nil
@@ -224,19 +386,29 @@ func (r *Runner) schedule() {
 			ticksInStep         = r.Cfg.StepDurationSec
 			totalRequestsFired  = 0
 			requestsFiredInTick = 0
+			tickStats           *SchedulerTickStats
 		)
 		if r.Cfg.SystemMode == UnboundRPS {
 			// analyze 100 samples by each attacker if no rps requirements
 			r.targetRPS = len(r.attackers) * 100
 			ticksInStep = 1
 		}
+		atomic.StoreInt64(&r.scheduledTick, int64(currentTick))
+		// there is no plan to measure against without rate limiter
+		if r.rl != nil {
+			tickStats = newSchedulerTickStats(currentTick, currentStep, r.targetRPS)
+		}
 		for {
 			select {
 			case <-r.TimeoutCtx.Done():
-				r.L.Infof("total requests fired: %d", totalRequestsFired)
+				r.L.Infof("total requests fired: %d, missed slots: %d", totalRequestsFired, atomic.LoadInt64(&r.MissedSlots))
 				close(r.next)
 				return
 			default:
+				if r.Paused() {
+					time.Sleep(pauseCheckInterval)
+					continue
+				}
 				if r.rl != nil {
 					r.rl.Take()
 				}
@@ -248,14 +420,25 @@ func (r *Runner) schedule() {
 					Tick:      currentTick,
 				}:
 				default:
+					if tickStats != nil {
+						tickStats.missed()
+						atomic.AddInt64(&r.MissedSlots, 1)
+					}
 					continue
 				}
 				totalRequestsFired++
 				requestsFiredInTick++
+				if tickStats != nil {
+					tickStats.fired(time.Now())
+				}
 				if requestsFiredInTick == r.targetRPS {
+					if tickStats != nil {
+						tickStats.done(time.Now())
+						r.storeSchedulerTickStats(tickStats)
+					}
 					currentTick += 1
+					atomic.StoreInt64(&r.scheduledTick, int64(currentTick))
 					requestsFiredInTick = 0
-					r.L.Infof("active goroutines: %d", runtime.NumGoroutine())
 					if currentTick%ticksInStep == 0 {
 						if r.rl != nil {
 							r.targetRPS += r.Cfg.StepRPS
@@ -264,6 +447,9 @@ func (r *Runner) schedule() {
 							r.L.Infof("next step: step -> %d, rps -> %d", currentStep, r.targetRPS)
 						}
 					}
+					if tickStats != nil {
+						tickStats = newSchedulerTickStats(currentTick, currentStep, r.targetRPS)
+					}
 				}
 			}
 		}
@@ -299,6 +485,12 @@ func (r *Runner) collectResults() {
 				if r.Cfg.ReportOptions.CSV {
 					r.Report.writeResultEntry(res, errorForReport)
 				}
+				if r.PromReporter != nil {
+					r.PromReporter.reportResult(res)
+				}
+				if r.es != nil {
+					r.es.Add(AggregatedNode, res)
+				}
 				r.processTickMetrics(res)
 			}
 		}
@@ -308,6 +500,11 @@ func (r *Runner) collectResults() {
 // scaleAttackers scaling attackers to meet targetRPS
 func (r *Runner) scaleAttackers(tm *TickMetrics) {
 	if r.Cfg.SystemMode == BoundRPSAutoscale && tm.Metrics.Rate < float64(tm.Samples[0].AttackToken.TargetRPS)*r.Cfg.AttackersScaleThreshold {
+		// some attackers were idle all the tick, so they are not the bottleneck
+		if tm.InFlight != nil && tm.InFlight.IdleMin > 0 {
+			r.L.Infof("target rate is not met, but %d attackers were idle, not scaling", tm.InFlight.IdleMin)
+			return
+		}
 		r.L.Infof("scaling attackers: %d", r.Cfg.AttackersScaleAmount)
 		for i := 0; i < r.Cfg.AttackersScaleAmount; i++ {
 			a := r.attackerPrototype.Clone(r)
@@ -315,6 +512,7 @@ func (r *Runner) scaleAttackers(tm *TickMetrics) {
 				log.Fatal(errAttackerSetup)
 			}
 			r.attackers = append(r.attackers, a)
+			atomic.AddInt64(&r.attackersCount, 1)
 			go attack(a, r)
 		}
 	}
@@ -327,10 +525,10 @@ func (r *Runner) processTickMetrics(res AttackResult) {
 	defer r.receivedTickMetricsMu.Unlock()
 	if _, ok := r.receivedTickMetrics[res.AttackToken.Tick]; !ok {
 		r.receivedTickMetrics[res.AttackToken.Tick] = &TickMetrics{
-			make([]AttackResult, 0),
-			NewMetrics(),
-			false,
+			Samples: make([]AttackResult, 0),
+			Metrics: NewMetrics(),
 		}
+		r.receivedTickMetrics[res.AttackToken.Tick].Metrics.SLO = NewSLOMetrics(r.Cfg.SLO)
 	}
 	currentTickMetrics := r.receivedTickMetrics[res.AttackToken.Tick]
 	currentTickMetrics.Samples = append(currentTickMetrics.Samples, res)
@@ -340,10 +538,29 @@ func (r *Runner) processTickMetrics(res AttackResult) {
 		}
 		for _, s := range currentTickMetrics.Samples {
 			currentTickMetrics.Metrics.add(s)
+			r.RunMetrics.add(s)
+			if _, ok := r.LabelMetrics[s.DoResult.RequestLabel]; !ok {
+				r.LabelMetrics[s.DoResult.RequestLabel] = NewMetrics()
+			}
+			r.LabelMetrics[s.DoResult.RequestLabel].add(s)
+		}
+		r.RunMetrics.update()
+		for _, m := range r.LabelMetrics {
+			m.update()
 		}
+		currentTickMetrics.Metrics.TargetRate = float64(res.AttackToken.TargetRPS)
 		currentTickMetrics.Metrics.update()
+		r.labelSteps.addTick(res.AttackToken.Step, res.AttackToken.Tick, res.AttackToken.TargetRPS, currentTickMetrics.Metrics.Rate, currentTickMetrics.Samples)
+		currentTickMetrics.Scheduler = r.popSchedulerTickStats(res.AttackToken.Tick)
+		currentTickMetrics.InFlight = r.popInFlightStats(res.AttackToken.Tick)
+		if r.ticksReported == 0 || currentTickMetrics.Metrics.Success < r.minTickSuccess {
+			r.minTickSuccess = currentTickMetrics.Metrics.Success
+		}
+		r.ticksReported++
 		if currentTickMetrics.Metrics.Success < r.Cfg.SuccessRatio {
 			r.L.Infof("success ratio threshold reached: %.4f < %.4f", currentTickMetrics.Metrics.Success, r.Cfg.SuccessRatio)
+			r.failureReason = fmt.Sprintf("success ratio threshold reached at tick %d: %.4f < %.4f",
+				res.AttackToken.Tick, currentTickMetrics.Metrics.Success, r.Cfg.SuccessRatio)
 			atomic.AddInt64(&r.Failed, 1)
 			r.CancelFunc()
 		}
@@ -376,11 +593,78 @@ func (r *Runner) processTickMetrics(res AttackResult) {
 				currentTickMetrics.Metrics.successLogEntry(),
 			)
 		}
+		if s := currentTickMetrics.Scheduler; s != nil {
+			r.L.Infof(
+				SchedulerTickTemplate,
+				s.AchievedRate,
+				s.TargetRPS,
+				s.Missed,
+				s.LagMean,
+				s.LagMax,
+			)
+		}
+		if s := currentTickMetrics.InFlight; s != nil {
+			r.L.Infof(
+				InFlightTickTemplate,
+				s.InFlightMin,
+				s.InFlightMean,
+				s.InFlightMax,
+				s.IdleMin,
+				s.IdleMean,
+				s.IdleMax,
+			)
+		}
+		if phases := currentTickMetrics.Metrics.HTTPPhases; phases.Requests > 0 && phases.Traced == 0 {
+			r.L.Infof(HTTPTotalTickTemplate, phases.Requests, phases.ConnReuse, phases.Total.P99)
+		} else if phases.Requests > 0 {
+			r.L.Infof(
+				HTTPPhasesTickTemplate,
+				phases.Requests,
+				phases.ConnReuse,
+				phases.DNS.P99,
+				phases.Connect.P99,
+				phases.TLS.P99,
+				phases.TTFB.P99,
+				phases.Total.P99,
+			)
+		}
+		if s := currentTickMetrics.Metrics.SLO; s != nil {
+			r.SLO.addTick(res.AttackToken.Step, s)
+			r.L.Infof(SLOTickTemplate, s, res.AttackToken.Step, r.SLO.Steps[res.AttackToken.Step])
+			if r.PromReporter != nil {
+				r.PromReporter.reportSLO("tick", s)
+				r.PromReporter.reportSLO("step", r.SLO.Steps[res.AttackToken.Step])
+				r.PromReporter.reportSLO("run", r.SLO.Run)
+			}
+		}
 		if r.Cfg.ReportOptions.CSV {
 			r.Report.writePercentilesEntry(res, currentTickMetrics.Metrics)
+			r.Report.writeDashboardTick(res.AttackToken, len(r.attackers), currentTickMetrics.Metrics, currentTickMetrics.Samples)
+			r.Report.writeSLOEntry(res, currentTickMetrics.Metrics.SLO)
+			r.Report.writeCustomMetricsEntry(res, currentTickMetrics.Metrics)
+			r.Report.writeHTTPPhasesEntry(res, currentTickMetrics.Metrics)
+			r.Report.writeSchedulerEntry(currentTickMetrics.Scheduler)
+			r.Report.writeInFlightEntry(currentTickMetrics.InFlight)
 		}
-		if r.Cfg.Prometheus != nil && r.Cfg.Prometheus.Enable {
-			r.PromReporter.reportTick(currentTickMetrics)
+		reportSinks(r.Sinks, SinkTick{
+			Runner:    r.Name,
+			Node:      AggregatedNode,
+			Step:      res.AttackToken.Step,
+			Tick:      res.AttackToken.Tick,
+			Attackers: int(atomic.LoadInt64(&r.attackersCount)),
+			Time:      time.Now(),
+			Metrics:   currentTickMetrics.Metrics,
+			Scheduler: currentTickMetrics.Scheduler,
+			InFlight:  currentTickMetrics.InFlight,
+		})
+		if r.otel != nil {
+			r.otel.addTick(res.AttackToken, len(r.attackers), currentTickMetrics.Metrics)
+		}
+		if r.live != nil {
+			r.live.publish(newLiveTick(AggregatedNode, res.AttackToken, len(r.attackers), currentTickMetrics.Metrics))
+		}
+		if r.tui != nil {
+			r.tui.addTick(res.AttackToken, len(r.attackers), currentTickMetrics.Metrics, currentTickMetrics.Samples)
 		}
 		r.scaleAttackers(currentTickMetrics)
 		currentTickMetrics.Reported = true
@@ -396,21 +680,38 @@ func (r *Runner) printErrors() {
 	}
 }
 
-// maxRPS calculate max rps for test among ticks
-func (r *Runner) maxRPS() float64 {
-	f, err := os.Open(r.Report.percLogFilename)
+// reportSLO logs SLO compliance of every step and the whole run
+func (r *Runner) reportSLO() {
+	if r.SLO == nil {
+		return
+	}
+	for _, step := range r.SLO.sortedSteps() {
+		r.L.Infof("slo step %d: %s", step, r.SLO.Steps[step])
+	}
+	r.L.Infof("slo run: %s", r.SLO.Run)
+	if !r.SLO.Run.Met() {
+		r.L.Warnf("slo is not met, error budget is exhausted")
+	}
+	if r.PromReporter != nil {
+		r.PromReporter.reportSLO("run", r.SLO.Run)
+	}
+}
+
+// reportKnee detects saturation knee when rps is increased by steps
+func (r *Runner) reportKnee() {
+	if r.Cfg.SystemMode != BoundRPS || r.Cfg.StepRPS == 0 {
+		return
+	}
+	knee, err := r.Report.detectKnee()
 	if err != nil {
-		log.Fatal(err)
-	}
-	rpsSlice := make([]float64, 0)
-	csvFile := csv.NewReader(f)
-	for {
-		line, err := csvFile.Read()
-		if err == io.EOF {
-			break
-		}
-		rps, _ := strconv.ParseFloat(line[2], 64)
-		rpsSlice = append(rpsSlice, rps)
+		r.L.Error(err)
+		return
 	}
-	return MaxRPS(rpsSlice)
+	r.Knee = knee
+	r.L.Infof("knee: %s", knee)
+}
+
+// maxRPS calculate max rps for test among ticks
+func (r *Runner) maxRPS() float64 {
+	return r.Report.maxRPS()
 }
@@ -2,9 +2,13 @@ package loaderbot
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -177,6 +181,61 @@ func TestCommonReportMetrics(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCommonReportPlotSkipsBrokenChart(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "plot_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		TestTimeSec:     2,
+		ReportOptions: &ReportOptions{
+			HTMLDir: "test_html",
+			CSVDir:  "test_csv",
+			CSV:     true,
+			PNG:     true,
+		},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	require.FileExists(t, r.Report.percsReportFilename)
+
+	require.NoError(t, os.Remove(r.Report.percsReportFilename))
+	require.NoError(t, ioutil.WriteFile(r.Report.inFlightLogFilename, []byte("Tick\n1,2\n"), 0644))
+	r.Report.plot(r.Knee)
+	require.FileExists(t, r.Report.percsReportFilename)
+}
+
+func TestCommonSLORunner(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "slo_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        8,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSVDir: "test_csv",
+			CSV:    false,
+		},
+		SLO: &SLO{
+			Objectives: []LatencyObjective{{Target: 0.99, ThresholdMs: 300}},
+			ApdexTMs:   300,
+		},
+	}, &ControlAttackerMock{}, nil)
+	r.controlled.Sleep = 500
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	// every request is slower than objective threshold, but tolerated by apdex
+	require.False(t, r.SLO.Run.Met())
+	require.Equal(t, 0.5, r.SLO.Run.Apdex)
+	require.Equal(t, 0.0, r.SLO.Run.Objectives[0].Compliance)
+	th := r.Thresholds()
+	require.Len(t, th, 2)
+	require.Equal(t, "slo 99% < 300ms", th[1].Name)
+	require.False(t, th[1].Passed)
+}
+
 func TestCommonGracefulPrometheusMultipleRunners(t *testing.T) {
 	cfg := &RunnerConfig{
 		Name:            "test_runner",
@@ -197,6 +256,9 @@ func TestCommonGracefulPrometheusMultipleRunners(t *testing.T) {
 	r2 := NewRunner(cfg, &ControlAttackerMock{}, nil)
 	_, err2 := r2.Run(context.TODO())
 	require.NoError(t, err2)
+	// every runner has own registry, so counters are not shared
+	require.GreaterOrEqual(t, testutil.ToFloat64(r2.PromReporter.promRequests.WithLabelValues("test_runner", "0", "none")), float64(r2.RunMetrics.Requests))
+	require.Equal(t, 10.0, testutil.ToFloat64(r2.PromReporter.promAttackers))
 }
 
 func TestCommonTypedInstance(t *testing.T) {
@@ -238,3 +300,22 @@ func TestCommonAutoscale(t *testing.T) {
 	// 100 attacker with 1 second blocked on request = 100 rps because clients are blocked
 	require.GreaterOrEqual(t, int(maxRPS), rps)
 }
+
+func TestCommonSchedulerMissedSlots(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "test_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       2,
+		AttackerTimeout: 1,
+		StartRPS:        20,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+	}, &ControlAttackerMock{}, nil)
+	// 2 attackers blocked for 500ms can't keep 20 rps
+	r.controlled.Sleep = 500
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	require.Greater(t, atomic.LoadInt64(&r.MissedSlots), int64(0))
+}
@@ -0,0 +1,299 @@
+package loaderbot
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/go-echarts/go-echarts/charts"
+)
+
+const (
+	// ScalingCsvFile scaling csv in ReportScaling format: label,parameter value,max sustainable rps
+	ScalingCsvFile = "scaling_%s.csv"
+	// ScalingHTMLFile scaling chart
+	ScalingHTMLFile = "scaling_%s.html"
+	// ScalingPNGFile scaling chart for slack
+	ScalingPNGFile = "scaling_%s.png"
+)
+
+// ScalingParam parameter varied by scaling experiment
+type ScalingParam int
+
+const (
+	// ScaleNodes runs cluster test on first N of ClusterOptions.Nodes
+	ScaleNodes ScalingParam = iota
+	// ScaleAttackers runs test with N attackers
+	ScaleAttackers
+	// ScaleReplicas passes N to setup hook, e.g. to scale target replicas, test config is not changed
+	ScaleReplicas
+)
+
+func (p ScalingParam) String() string {
+	switch p {
+	case ScaleNodes:
+		return "Nodes"
+	case ScaleAttackers:
+		return "Attackers"
+	case ScaleReplicas:
+		return "Replicas"
+	default:
+		return "ScalingParam(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// ScalingExperiment runs the same test for every parameter value and reports max sustainable rps of each request label
+type ScalingExperiment struct {
+	// Name of experiment, used in file names
+	Name string
+	// Cfg test config, every cell runs its copy with csv report enabled
+	Cfg *RunnerConfig
+	// Attacker of local runner, nodes use Cfg.InstanceType in cluster mode
+	Attacker Attack
+	// Param varied parameter
+	Param ScalingParam
+	// Values of the parameter, x axis of scaling charts
+	Values []int
+	// Setup hook called before every cell with parameter value and cell config, cell fails if it returns error
+	Setup func(value int, cfg *RunnerConfig) error
+	// OutDir scaling csv and charts dir, default is results_csv
+	OutDir string
+}
+
+// ScalingCell result of one parameter value
+type ScalingCell struct {
+	Value int
+	// MaxRPS max sustainable rps by request label
+	MaxRPS map[string]float64
+}
+
+// Run runs all cells in order, writes scaling csv and renders scaling charts
+func (e *ScalingExperiment) Run(ctx context.Context) ([]ScalingCell, error) {
+	if len(e.Values) == 0 {
+		return nil, errors.New("no scaling values")
+	}
+	if e.OutDir == "" {
+		e.OutDir = "results_csv"
+	}
+	_ = os.MkdirAll(e.OutDir, os.ModePerm)
+	cells := make([]ScalingCell, 0, len(e.Values))
+	for _, v := range e.Values {
+		cfg, err := e.cellConfig(v)
+		if err != nil {
+			return cells, err
+		}
+		if e.Setup != nil {
+			if err := e.Setup(v, cfg); err != nil {
+				return cells, fmt.Errorf("scaling setup %s=%d: %v", e.Param, v, err)
+			}
+		}
+		rps, err := e.runCell(ctx, cfg)
+		if err != nil {
+			return cells, fmt.Errorf("scaling run %s=%d: %v", e.Param, v, err)
+		}
+		cells = append(cells, ScalingCell{Value: v, MaxRPS: rps})
+	}
+	if err := WriteScalingCSV(e.CSVPath(), cells); err != nil {
+		return cells, err
+	}
+	return cells, e.plot()
+}
+
+// CSVPath scaling csv of the experiment
+func (e *ScalingExperiment) CSVPath() string {
+	return path.Join(e.OutDir, fmt.Sprintf(ScalingCsvFile, e.Name))
+}
+
+// cellConfig copies test config for parameter value, report options are copied to not change shared config
+func (e *ScalingExperiment) cellConfig(v int) (*RunnerConfig, error) {
+	cfg := *e.Cfg
+	ro := ReportOptions{CSVDir: "results_csv", HTMLDir: "results_html", PNG: true}
+	if e.Cfg.ReportOptions != nil {
+		ro = *e.Cfg.ReportOptions
+	}
+	// every cell is reported as usual run
+	ro.CSV = true
+	cfg.ReportOptions = &ro
+	switch e.Param {
+	case ScaleNodes:
+		nodes := clusterNodes(e.Cfg)
+		if v <= 0 || v > len(nodes) {
+			return nil, fmt.Errorf("can't run on %d nodes, cluster options have %d", v, len(nodes))
+		}
+		cfg.ClusterOptions = &ClusterOptions{Nodes: nodes[:v]}
+	case ScaleAttackers:
+		cfg.Attackers = v
+	}
+	return &cfg, nil
+}
+
+func clusterNodes(cfg *RunnerConfig) []string {
+	if cfg.ClusterOptions == nil {
+		return nil
+	}
+	return cfg.ClusterOptions.Nodes
+}
+
+// runCell runs test on cluster if nodes are set, otherwise on local runner
+func (e *ScalingExperiment) runCell(ctx context.Context, cfg *RunnerConfig) (map[string]float64, error) {
+	if len(clusterNodes(cfg)) > 0 {
+		c := NewClusterClient(cfg)
+		if c.failed {
+			return nil, errors.New("cluster is busy")
+		}
+		c.Run()
+		return c.labelSteps.sustainableRPS(cfg.SuccessRatio, cfg.Name), nil
+	}
+	r := NewRunner(cfg, e.Attacker, nil)
+	if _, err := r.Run(ctx); err != nil {
+		return nil, err
+	}
+	return r.labelSteps.sustainableRPS(cfg.SuccessRatio, cfg.Name), nil
+}
+
+// SustainableRPS rps of last healthy step if knee is found,
+// otherwise max rps of steps which kept success ratio
+func SustainableRPS(run *RunData, knee *Knee, successRatio float64) float64 {
+	if knee != nil && knee.LastHealthy != nil {
+		return knee.LastHealthy.RPS
+	}
+	var max float64
+	for _, s := range run.Steps {
+		if s.Success >= successRatio && s.RPS > max {
+			max = s.RPS
+		}
+	}
+	return max
+}
+
+// labelStepSums sums of label tick metrics in a step, target is a sum of label shares of tick target rps
+type labelStepSums struct {
+	s      StepSummary
+	target float64
+}
+
+// labelSteps per step metrics of every request label, percentiles are means of tick percentiles as in percs log
+type labelSteps map[string]map[int]*labelStepSums
+
+// addTick adds reported tick, label rps and target rps are label share of tick rps and target rps
+func (ls labelSteps) addTick(step int, tick int, targetRPS int, rate float64, samples []AttackResult) {
+	if len(samples) == 0 {
+		return
+	}
+	labels := make(map[string]*Metrics)
+	for _, s := range samples {
+		if _, ok := labels[s.DoResult.RequestLabel]; !ok {
+			labels[s.DoResult.RequestLabel] = NewMetrics()
+		}
+		labels[s.DoResult.RequestLabel].add(s)
+	}
+	for l, m := range labels {
+		m.update()
+		share := float64(m.Requests) / float64(len(samples))
+		if _, ok := ls[l]; !ok {
+			ls[l] = make(map[int]*labelStepSums)
+		}
+		sums, ok := ls[l][step]
+		if !ok {
+			sums = &labelStepSums{s: StepSummary{Step: step, StartTick: tick}}
+			ls[l][step] = sums
+		}
+		if tick < sums.s.StartTick {
+			sums.s.StartTick = tick
+		}
+		sums.s.Ticks++
+		sums.s.RPS += rate * share
+		sums.s.P50 += durationMs(m.Latencies.P50)
+		sums.s.P95 += durationMs(m.Latencies.P95)
+		sums.s.P99 += durationMs(m.Latencies.P99)
+		sums.s.Success += m.Success
+		sums.target += float64(targetRPS) * share
+	}
+}
+
+// steps label step summaries sorted by step
+func (ls labelSteps) steps(label string) []StepSummary {
+	res := make([]StepSummary, 0, len(ls[label]))
+	for _, sums := range ls[label] {
+		s := sums.s
+		ticks := float64(s.Ticks)
+		s.TargetRPS = int(math.Round(sums.target / ticks))
+		s.RPS /= ticks
+		s.P50 /= ticks
+		s.P95 /= ticks
+		s.P99 /= ticks
+		s.Success /= ticks
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Step < res[j].Step
+	})
+	return res
+}
+
+// sustainableRPS sustainable rps of every label found by its own steps and knee,
+// requests without label are reported by test name
+func (ls labelSteps) sustainableRPS(successRatio float64, name string) map[string]float64 {
+	res := make(map[string]float64)
+	for l := range ls {
+		steps := ls.steps(l)
+		rps := SustainableRPS(&RunData{Steps: steps}, DetectKnee(steps, DefaultKneeOptions), successRatio)
+		if l == "" {
+			l = name
+		}
+		res[l] = rps
+	}
+	if len(res) == 0 {
+		res[name] = 0
+	}
+	return res
+}
+
+// WriteScalingCSV writes cells in ReportScaling format, labels are sorted
+func WriteScalingCSV(path string, cells []ScalingCell) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	for _, c := range cells {
+		labels := make([]string, 0, len(c.MaxRPS))
+		for l := range c.MaxRPS {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			if err := w.Write([]string{l, strconv.Itoa(c.Value), strconv.FormatFloat(c.MaxRPS[l], 'f', 2, 64)}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// plot renders scaling html and png charts, x axis is named by varied parameter
+func (e *ScalingExperiment) plot() error {
+	title := fmt.Sprintf("%s scaling by %s", e.Name, e.Param)
+	line, err := ScalingChart(e.CSVPath(), title)
+	if err != nil {
+		return err
+	}
+	line.SetGlobalOptions(charts.XAxisOpts{Name: e.Param.String()})
+	RenderEChart(line, path.Join(e.OutDir, fmt.Sprintf(ScalingHTMLFile, e.Name)))
+	png, err := SlackScalingChart(e.CSVPath())
+	if err != nil {
+		return err
+	}
+	png.Title = title
+	png.XAxis.Name = e.Param.String()
+	RenderChart(png, path.Join(e.OutDir, fmt.Sprintf(ScalingPNGFile, e.Name)))
+	return nil
+}
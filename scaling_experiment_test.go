@@ -0,0 +1,107 @@
+package loaderbot
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonSustainableRPS(t *testing.T) {
+	run := &RunData{Steps: []StepSummary{
+		{Step: 1, TargetRPS: 10, RPS: 10, Success: 1},
+		{Step: 2, TargetRPS: 20, RPS: 19, Success: 1},
+		{Step: 3, TargetRPS: 30, RPS: 25, Success: 0.5},
+	}}
+	require.Equal(t, 19.0, SustainableRPS(run, nil, 0.9))
+	require.Equal(t, 25.0, SustainableRPS(run, nil, 0))
+	require.Equal(t, 10.0, SustainableRPS(run, &Knee{LastHealthy: &run.Steps[0]}, 0.9))
+}
+
+func TestCommonLabelsSustainableRPS(t *testing.T) {
+	ls := make(labelSteps)
+	for step := 1; step <= 3; step++ {
+		samples := make([]AttackResult, 0)
+		for i := 0; i < 10; i++ {
+			res := AttackResult{Elapsed: 10 * time.Millisecond, DoResult: DoResult{RequestLabel: "fast"}}
+			if i%2 == 1 {
+				res.DoResult.RequestLabel = "slow"
+				// slow label fails from step 2, fast one keeps up
+				if step > 1 {
+					res.DoResult.Error = "timeout"
+				}
+			}
+			samples = append(samples, res)
+		}
+		ls.addTick(step, step, step*100, float64(step*100), samples)
+	}
+	steps := ls.steps("slow")
+	require.Len(t, steps, 3)
+	require.Equal(t, 150, steps[2].TargetRPS)
+	require.Equal(t, 150.0, steps[2].RPS)
+	require.Equal(t, 0.0, steps[2].Success)
+	require.Equal(t, map[string]float64{"fast": 150, "slow": 50}, ls.sustainableRPS(0.9, "run"))
+	require.Equal(t, map[string]float64{"run": 0}, make(labelSteps).sustainableRPS(0.9, "run"))
+}
+
+func TestCommonScalingExperiment(t *testing.T) {
+	setups := make([]int, 0)
+	e := &ScalingExperiment{
+		Name: "attackers",
+		Cfg: &RunnerConfig{
+			Name:            "scaling_runner",
+			SystemMode:      BoundRPS,
+			Attackers:       1,
+			AttackerTimeout: 1,
+			StartRPS:        10,
+			TestTimeSec:     3,
+			ReportOptions: &ReportOptions{
+				CSVDir:  "test_csv",
+				HTMLDir: "test_html",
+				PNG:     false,
+			},
+		},
+		Attacker: &ControlAttackerMock{},
+		Param:    ScaleAttackers,
+		Values:   []int{2, 10},
+		Setup: func(value int, cfg *RunnerConfig) error {
+			require.Equal(t, value, cfg.Attackers)
+			require.True(t, cfg.ReportOptions.CSV)
+			setups = append(setups, value)
+			return nil
+		},
+		OutDir: "test_csv",
+	}
+	cells, err := e.Run(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 10}, setups)
+	require.False(t, e.Cfg.ReportOptions.CSV)
+	require.Len(t, cells, 2)
+	for _, c := range cells {
+		require.Len(t, c.MaxRPS, 1)
+		// tick rate is measured between first and last request of a tick
+		require.InDelta(t, 10, c.MaxRPS["scaling_runner"], 1.5)
+	}
+	data, err := ioutil.ReadFile(e.CSVPath())
+	require.NoError(t, err)
+	require.Regexp(t, `^scaling_runner,2,\d+\.\d{2}\nscaling_runner,10,\d+\.\d{2}\n$`, string(data))
+	require.FileExists(t, path.Join("test_csv", "scaling_attackers.html"))
+	require.FileExists(t, path.Join("test_csv", "scaling_attackers.png"))
+
+	// failed setup stops experiment
+	e.Param = ScaleReplicas
+	e.Setup = func(value int, cfg *RunnerConfig) error {
+		return errors.New("can't scale target")
+	}
+	cells, err = e.Run(context.TODO())
+	require.EqualError(t, err, "scaling setup Replicas=2: can't scale target")
+	require.Empty(t, cells)
+
+	e.Param = ScaleNodes
+	_, err = e.Run(context.TODO())
+	require.EqualError(t, err, "can't run on 2 nodes, cluster options have 0")
+}
@@ -0,0 +1,80 @@
+package loaderbot
+
+import (
+	"time"
+)
+
+// SchedulerTickStats schedule plan execution for a tick,
+// shows when attackers pool rather than the target limits the rate
+type SchedulerTickStats struct {
+	Tick      int
+	Step      int
+	TargetRPS int
+	// Fired amount of attacks sent to attackers
+	Fired int
+	// Missed amount of rate limiter slots skipped because no attacker was ready
+	Missed int
+	// AchievedRate rate at which attacks were actually sent
+	AchievedRate float64
+	// LagMean mean delay of sent attacks against the rate limiter plan
+	LagMean time.Duration
+	// LagMax max delay of sent attacks against the rate limiter plan
+	LagMax time.Duration
+
+	start    time.Time
+	lagTotal time.Duration
+}
+
+func newSchedulerTickStats(tick, step, targetRPS int) *SchedulerTickStats {
+	return &SchedulerTickStats{
+		Tick:      tick,
+		Step:      step,
+		TargetRPS: targetRPS,
+		start:     time.Now(),
+	}
+}
+
+// fired records sent attack, limiter plans n-th attack of a tick at tick start + n/targetRPS
+func (s *SchedulerTickStats) fired(now time.Time) {
+	s.Fired++
+	planned := s.start.Add(time.Duration(s.Fired) * time.Second / time.Duration(s.TargetRPS))
+	if lag := now.Sub(planned); lag > 0 {
+		s.lagTotal += lag
+		if lag > s.LagMax {
+			s.LagMax = lag
+		}
+	}
+}
+
+func (s *SchedulerTickStats) missed() {
+	s.Missed++
+}
+
+// done computes rate and lag when all attacks of a tick are sent
+func (s *SchedulerTickStats) done(now time.Time) {
+	if secs := now.Sub(s.start).Seconds(); secs > 0 {
+		s.AchievedRate = float64(s.Fired) / secs
+	}
+	if s.Fired > 0 {
+		s.LagMean = s.lagTotal / time.Duration(s.Fired)
+	}
+}
+
+// storeSchedulerTickStats saves completed tick stats until tick results are reported
+func (r *Runner) storeSchedulerTickStats(s *SchedulerTickStats) {
+	r.schedulerTickStatsMu.Lock()
+	defer r.schedulerTickStatsMu.Unlock()
+	r.schedulerTickStats[s.Tick] = s
+}
+
+// popSchedulerTickStats returns completed tick stats, nil if tick is not yet completed by scheduler
+func (r *Runner) popSchedulerTickStats(tick int) *SchedulerTickStats {
+	r.schedulerTickStatsMu.Lock()
+	defer r.schedulerTickStatsMu.Unlock()
+	s, ok := r.schedulerTickStats[tick]
+	if !ok {
+		return nil
+	}
+	delete(r.schedulerTickStats, tick)
+	return s
+}
@@ -0,0 +1,337 @@
+package loaderbot
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignificanceOptions statistical tests options
+type SignificanceOptions struct {
+	// Quantiles to compute bootstrap confidence intervals for, default is 0.5, 0.95, 0.99
+	Quantiles []float64
+	// Iterations bootstrap resamples, default is 1000
+	Iterations int
+	// Confidence level of bootstrap intervals, default is 0.95
+	Confidence float64
+	// Alpha Mann-Whitney test significance level, default is 0.05
+	Alpha float64
+	// MaxSamples samples are thinned to this amount before bootstrap, default is 10000
+	MaxSamples int
+	// Seed of resampling, results are reproducible for the same seed
+	Seed int64
+}
+
+func (o *SignificanceOptions) defaults() {
+	if len(o.Quantiles) == 0 {
+		o.Quantiles = []float64{0.5, 0.95, 0.99}
+	}
+	if o.Iterations == 0 {
+		o.Iterations = 1000
+	}
+	if o.Confidence == 0 {
+		o.Confidence = 0.95
+	}
+	if o.Alpha == 0 {
+		o.Alpha = 0.05
+	}
+	if o.MaxSamples == 0 {
+		o.MaxSamples = 10000
+	}
+	if o.Seed == 0 {
+		o.Seed = 1
+	}
+}
+
+// QuantileDiff bootstrap confidence interval of candidate minus baseline quantile, ms
+type QuantileDiff struct {
+	Quantile  float64
+	Baseline  float64
+	Candidate float64
+	Low       float64
+	High      float64
+	// Significant true if the whole interval is above zero, candidate is slower
+	Significant bool
+}
+
+// SignificanceResult statistical comparison of candidate latencies with baseline
+type SignificanceResult struct {
+	BaselineSamples  int
+	CandidateSamples int
+	// U Mann-Whitney statistic of candidate sample
+	U float64
+	// Z normal approximation of U
+	Z float64
+	// P one-sided p-value of candidate latencies being stochastically greater than baseline
+	P         float64
+	Quantiles []QuantileDiff
+	// Regression true if distributions differ significantly and any quantile is significantly slower
+	Regression bool
+}
+
+// LoadLatencies loads latencies of successful requests from raw requests csv or binary log, ms
+func LoadLatencies(path string) ([]float64, error) {
+	if strings.HasSuffix(path, ".lbr.gz") {
+		return loadBinaryLatencies(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	// skip csv header
+	_, _ = reader.Read()
+	res := make([]float64, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(ResultsCsvHeader) && len(record) != legacyResultsCsvColumns {
+			return nil, errors.New("malformed csv")
+		}
+		if record[5] != "ok" {
+			continue
+		}
+		elapsed, err := time.ParseDuration(record[3])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, durationMs(elapsed))
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no successful requests, nothing to compare")
+	}
+	return res, nil
+}
+
+// LoadStepLatencies loads latencies of successful requests from raw requests csv or binary log
+// keyed by step or target rps level, ms
+func LoadStepLatencies(path string, alignBy string) (map[int][]float64, error) {
+	if !strings.HasSuffix(path, ".lbr.gz") {
+		hasSteps, err := resultsCsvHasSteps(path)
+		if err != nil {
+			return nil, err
+		}
+		if !hasSteps {
+			return nil, fmt.Errorf("%s has no steps, it's written by older version, use binary results log", path)
+		}
+	}
+	res := make(map[int][]float64)
+	err := ReadRawResults(path, func(r AttackResult) error {
+		if r.DoResult.Error != "" {
+			return nil
+		}
+		key := r.AttackToken.Step
+		if alignBy == AlignByRPS {
+			key = r.AttackToken.TargetRPS
+		}
+		res[key] = append(res[key], durationMs(r.Elapsed))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no successful requests, nothing to compare")
+	}
+	return res, nil
+}
+
+// resultsCsvHasSteps checks requests csv header for step and target rps columns
+func resultsCsvHasSteps(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header, err := csv.NewReader(f).Read()
+	if err != nil {
+		return false, err
+	}
+	return len(header) == len(ResultsCsvHeader), nil
+}
+
+func loadBinaryLatencies(path string) ([]float64, error) {
+	res := make([]float64, 0)
+	err := ReadResultsLog(path, func(r AttackResult) error {
+		if r.DoResult.Error == "" {
+			res = append(res, durationMs(r.Elapsed))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no successful requests, nothing to compare")
+	}
+	return res, nil
+}
+
+// CompareLatencies tests whether candidate latencies are significantly worse than baseline
+// using Mann-Whitney U test and bootstrap confidence intervals of quantiles differences
+func CompareLatencies(baseline, candidate []float64, opts SignificanceOptions) *SignificanceResult {
+	opts.defaults()
+	res := &SignificanceResult{
+		BaselineSamples:  len(baseline),
+		CandidateSamples: len(candidate),
+	}
+	res.U, res.Z, res.P = MannWhitneyU(candidate, baseline)
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	base := thin(baseline, opts.MaxSamples)
+	cand := thin(candidate, opts.MaxSamples)
+	sort.Float64s(base)
+	sort.Float64s(cand)
+	diffs := make([][]float64, len(opts.Quantiles))
+	baseResample := make([]float64, len(base))
+	candResample := make([]float64, len(cand))
+	for i := 0; i < opts.Iterations; i++ {
+		resample(rng, base, baseResample)
+		resample(rng, cand, candResample)
+		sort.Float64s(baseResample)
+		sort.Float64s(candResample)
+		for qi, q := range opts.Quantiles {
+			diffs[qi] = append(diffs[qi], quantileSorted(candResample, q)-quantileSorted(baseResample, q))
+		}
+	}
+	var anySlower bool
+	for qi, q := range opts.Quantiles {
+		sort.Float64s(diffs[qi])
+		tail := (1 - opts.Confidence) / 2
+		d := QuantileDiff{
+			Quantile:  q,
+			Baseline:  quantileSorted(base, q),
+			Candidate: quantileSorted(cand, q),
+			Low:       quantileSorted(diffs[qi], tail),
+			High:      quantileSorted(diffs[qi], 1-tail),
+		}
+		d.Significant = d.Low > 0
+		anySlower = anySlower || d.Significant
+		res.Quantiles = append(res.Quantiles, d)
+	}
+	res.Regression = res.P < opts.Alpha && anySlower
+	return res
+}
+
+// MannWhitneyU computes U statistic of sample a against b, its z-score with ties correction
+// and one-sided p-value of a being stochastically greater than b
+func MannWhitneyU(a, b []float64) (u float64, z float64, p float64) {
+	type obs struct {
+		v     float64
+		fromA bool
+	}
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, 1
+	}
+	all := make([]obs, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].v < all[j].v
+	})
+	var rankSumA, tiesCorrection float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		// tied values share the average rank
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tiesCorrection += t*t*t - t
+		i = j
+	}
+	u = rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tiesCorrection/(n*(n-1))))
+	if sigma == 0 {
+		return u, 0, 1
+	}
+	z = (u - mean) / sigma
+	p = 0.5 * math.Erfc(z/math.Sqrt2)
+	return u, z, p
+}
+
+// Markdown renders significance test results
+func (s *SignificanceResult) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Mann-Whitney: U = %.0f, z = %.3f, p = %.4f, samples %d -> %d, significant regression: %t\n\n",
+		s.U, s.Z, s.P, s.BaselineSamples, s.CandidateSamples, s.Regression)
+	b.WriteString("| quantile | baseline, ms | candidate, ms | diff CI, ms | significant |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, q := range s.Quantiles {
+		fmt.Fprintf(&b, "| %.3f | %.2f | %.2f | [%.2f, %.2f] | %t |\n", q.Quantile, q.Baseline, q.Candidate, q.Low, q.High, q.Significant)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// slower true if quantile q is significantly slower, quantiles which were not tested are considered slower
+func (s *SignificanceResult) slower(q float64) bool {
+	for _, d := range s.Quantiles {
+		if d.Quantile == q {
+			return d.Significant
+		}
+	}
+	return true
+}
+
+// thin takes evenly spaced samples if there are more than max
+func thin(samples []float64, max int) []float64 {
+	if len(samples) <= max {
+		res := make([]float64, len(samples))
+		copy(res, samples)
+		return res
+	}
+	res := make([]float64, 0, max)
+	step := float64(len(samples)) / float64(max)
+	for i := 0; i < max; i++ {
+		res = append(res, samples[int(float64(i)*step)])
+	}
+	return res
+}
+
+func resample(rng *rand.Rand, from, to []float64) {
+	for i := range to {
+		to[i] = from[rng.Intn(len(from))]
+	}
+}
+
+// quantileSorted nearest rank quantile of sorted samples
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
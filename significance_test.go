@@ -0,0 +1,132 @@
+package loaderbot
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func normalLatencies(seed int64, n int, mean, stddev float64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	res := make([]float64, n)
+	for i := range res {
+		res[i] = mean + rng.NormFloat64()*stddev
+	}
+	return res
+}
+
+func TestCommonMannWhitneyU(t *testing.T) {
+	// classic example, all values of a are greater than b
+	u, _, p := MannWhitneyU([]float64{6, 7, 8, 9}, []float64{1, 2, 3, 4, 5})
+	require.Equal(t, float64(20), u)
+	require.Less(t, p, 0.01)
+
+	_, z, p := MannWhitneyU([]float64{1, 2, 3}, []float64{1, 2, 3})
+	require.Equal(t, float64(0), z)
+	require.Equal(t, 0.5, p)
+}
+
+func TestCommonCompareLatencies(t *testing.T) {
+	base := normalLatencies(1, 2000, 100, 10)
+	noise := normalLatencies(2, 2000, 100, 10)
+	slow := normalLatencies(3, 2000, 110, 10)
+
+	res := CompareLatencies(base, noise, SignificanceOptions{Iterations: 200})
+	require.False(t, res.Regression)
+
+	res = CompareLatencies(base, slow, SignificanceOptions{Iterations: 200})
+	require.True(t, res.Regression)
+	require.Len(t, res.Quantiles, 3)
+	require.Greater(t, res.Quantiles[0].Low, float64(0))
+}
+
+func TestCommonApplySignificance(t *testing.T) {
+	baseRun := &RunData{Name: "base", Steps: []StepSummary{
+		{Step: 1, RPS: 100, P50: 100, P95: 120, P99: 130, Success: 1},
+		{Step: 2, RPS: 100, P50: 100, P95: 120, P99: 130, Success: 1},
+		{Step: 3, RPS: 100, P50: 100, P95: 120, P99: 130, Success: 1},
+	}}
+	candRun := &RunData{Name: "cand", Steps: []StepSummary{
+		{Step: 1, RPS: 100, P50: 100, P95: 120, P99: 170, Success: 1},
+		{Step: 2, RPS: 100, P50: 115, P95: 120, P99: 200, Success: 1},
+		{Step: 3, RPS: 100, P50: 115, P95: 120, P99: 200, Success: 1},
+	}}
+	c := Compare(baseRun, []*RunData{candRun}, CompareOptions{Tolerances: DefaultTolerances})
+	require.False(t, c.Passed)
+
+	// step 2 is slower as a whole, step 3 has the same distribution but 2% slowest requests are even slower
+	tail := normalLatencies(3, 2000, 100, 10)
+	for i := range tail {
+		if tail[i] > 120 {
+			tail[i] += 30
+		}
+	}
+	err := c.ApplySignificance(
+		map[int][]float64{
+			1: normalLatencies(1, 2000, 100, 10),
+			2: normalLatencies(4, 2000, 100, 10),
+			3: normalLatencies(5, 2000, 100, 10),
+		},
+		[]map[int][]float64{{
+			1: normalLatencies(2, 2000, 100, 10),
+			2: normalLatencies(6, 2000, 110, 10),
+			3: tail,
+		}},
+		SignificanceOptions{Iterations: 200},
+	)
+	require.NoError(t, err)
+	require.False(t, c.Passed)
+	step1, step2, step3 := c.Runs[0].Steps[0], c.Runs[0].Steps[1], c.Runs[0].Steps[2]
+	require.Empty(t, step1.Regressions)
+	require.Len(t, step1.Insignificant, 1)
+	require.Len(t, step2.Regressions, 2)
+	require.Empty(t, step2.Insignificant)
+	// p99 interval is above zero, but Mann-Whitney test finds no significant difference
+	require.True(t, step3.Significance.slower(0.99))
+	require.False(t, step3.Significance.Regression)
+	require.Empty(t, step3.Regressions)
+	require.Len(t, step3.Insignificant, 2)
+	require.Contains(t, c.Markdown(), "not significant")
+	require.Contains(t, c.Markdown(), "step 2: Mann-Whitney")
+}
+
+func TestCommonLoadStepLatenciesCSV(t *testing.T) {
+	path := "test_csv/requests_steps.csv"
+	require.NoError(t, os.MkdirAll("test_csv", os.ModePerm))
+	defer os.Remove(path)
+	rows := [][]string{
+		ResultsCsvHeader,
+		{"req", "0", "1000000", "1ms", "200", "ok", "1", "10"},
+		{"req", "0", "2000000", "2ms", "200", "ok", "1", "10"},
+		{"req", "0", "3000000", "3ms", "500", "err", "2", "20"},
+		{"req", "0", "4000000", "4ms", "200", "ok", "2", "20"},
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		b.WriteString(strings.Join(r, ",") + "\n")
+	}
+	require.NoError(t, ioutil.WriteFile(path, []byte(b.String()), 0644))
+
+	res, err := LoadStepLatencies(path, AlignByStep)
+	require.NoError(t, err)
+	require.Equal(t, map[int][]float64{1: {1, 2}, 2: {4}}, res)
+	res, err = LoadStepLatencies(path, AlignByRPS)
+	require.NoError(t, err)
+	require.Equal(t, map[int][]float64{10: {1, 2}, 20: {4}}, res)
+
+	// older requests csv has no steps
+	b.Reset()
+	for _, r := range rows {
+		b.WriteString(strings.Join(r[:legacyResultsCsvColumns], ",") + "\n")
+	}
+	require.NoError(t, ioutil.WriteFile(path, []byte(b.String()), 0644))
+	_, err = LoadStepLatencies(path, AlignByStep)
+	require.Error(t, err)
+	lat, err := LoadLatencies(path)
+	require.NoError(t, err)
+	require.Len(t, lat, 3)
+}
@@ -109,7 +109,8 @@ func ResponsesChart(chartTitle string, path string) (*chart.Chart, error) {
 		if err != nil {
 			return nil, err
 		}
-		if len(record) != 6 {
+		// step, target rps and success columns are optional
+		if len(record) < 6 {
 			return nil, errors.New("malformed csv")
 		}
 
@@ -0,0 +1,297 @@
+package loaderbot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/wcharczuk/go-chart"
+)
+
+const (
+	// SlackChartFile chart rendered for slack message, latest run of a runner overwrites it
+	SlackChartFile = "slack_%s_%s.png"
+	// slackTopErrors amount of most frequent errors in message
+	slackTopErrors = 3
+)
+
+// SlackMessage incoming webhook payload
+type SlackMessage struct {
+	// Text fallback for notifications
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock section or image block
+type SlackBlock struct {
+	Type     string     `json:"type"`
+	Text     *SlackText `json:"text,omitempty"`
+	ImageURL string     `json:"image_url,omitempty"`
+	AltText  string     `json:"alt_text,omitempty"`
+}
+
+// SlackText mrkdwn or plain text of a block
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackChart chart rendered for message
+type SlackChart struct {
+	Name string
+	// Path rendered png file
+	Path string
+	PNG  []byte
+}
+
+// NewSlackMessage run verdict, summary, failed thresholds and top errors,
+// charts are shown as image blocks if imagesURL is set
+func NewSlackMessage(m *RunManifest, charts []SlackChart, imagesURL string) *SlackMessage {
+	title := fmt.Sprintf("Load test %s: %s", m.Name, verdict(m.Passed))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n", slackEscape(title))
+	if m.FailureReason != "" {
+		fmt.Fprintf(&b, "Failure reason: %s\n", slackEscape(m.FailureReason))
+	}
+	s := m.Summary
+	fmt.Fprintf(&b, "requests: %d, success: %.4f, max rps: %.2f\n", s.Requests, s.Success, s.MaxRPS)
+	fmt.Fprintf(&b, "p50: %v, p95: %v, p99: %v, max: %v", s.Latencies.P50, s.Latencies.P95, s.Latencies.P99, s.Latencies.Max)
+	if s.untrustworthy() {
+		b.WriteString("\nload generator was saturated, results are untrustworthy")
+	}
+	blocks := []SlackBlock{markdownBlock(b.String())}
+
+	b.Reset()
+	for _, t := range m.Thresholds {
+		fmt.Fprintf(&b, "%s %s: %s\n", verdict(t.Passed), slackEscape(t.Name), slackEscape(t.Message))
+	}
+	for _, e := range topErrors(s.Errors, slackTopErrors) {
+		fmt.Fprintf(&b, "error x%d: %s\n", s.Errors[e], slackEscape(e))
+	}
+	if b.Len() > 0 {
+		blocks = append(blocks, markdownBlock(strings.TrimSuffix(b.String(), "\n")))
+	}
+	if imagesURL != "" {
+		for _, c := range charts {
+			blocks = append(blocks, SlackBlock{
+				Type:     "image",
+				ImageURL: strings.TrimSuffix(imagesURL, "/") + "/" + filepath.Base(c.Path),
+				AltText:  c.Name,
+			})
+		}
+	}
+	return &SlackMessage{Text: title, Blocks: blocks}
+}
+
+func markdownBlock(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}}
+}
+
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// SlackWebhook posts messages to slack compatible incoming webhook
+type SlackWebhook struct {
+	cfg    *Slack
+	client *http.Client
+}
+
+func NewSlackWebhook(cfg *Slack) *SlackWebhook {
+	return &SlackWebhook{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second},
+	}
+}
+
+// Post sends message as json, charts are uploaded to channel if bot token is set and images url is not,
+// network errors, 429 and 5xx responses are retried
+func (s *SlackWebhook) Post(msg *SlackMessage, charts []SlackChart) error {
+	payload, err := jsoniter.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	err = s.retry(func() (bool, error) {
+		_, retry, err := s.send(s.cfg.WebhookURL, "application/json", payload, "")
+		return retry, err
+	})
+	if err != nil || s.cfg.ImagesURL != "" || s.cfg.BotToken == "" {
+		return err
+	}
+	for _, c := range charts {
+		c := c
+		if err := s.retry(func() (bool, error) { return s.upload(c) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SlackWebhook) retry(fn func() (bool, error)) error {
+	var err error
+	for attempt := 0; attempt <= s.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(s.cfg.RetryIntervalMs) * time.Millisecond)
+		}
+		var retry bool
+		retry, err = fn()
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// send posts body, bearer token is set if not empty, returns response body and if failed request can be retried
+func (s *SlackWebhook) send(url string, contentType string, body []byte, token string) ([]byte, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer res.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode/100 == 2 {
+		return respBody, false, nil
+	}
+	err = fmt.Errorf("slack responded %d: %s", res.StatusCode, strings.TrimSpace(string(respBody)))
+	return nil, res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+// slackAPIResponse web api responds 200 with ok false on errors
+type slackAPIResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// slackUploadTicket files.getUploadURLExternal response
+type slackUploadTicket struct {
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// slackCompleteUpload files.completeUploadExternal request, shares uploaded files to channel
+type slackCompleteUpload struct {
+	Files     []slackUploadedFile `json:"files"`
+	ChannelID string              `json:"channel_id"`
+}
+
+type slackUploadedFile struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// upload uploads chart to channel: upload url is requested with files.getUploadURLExternal,
+// file is posted to it and shared with files.completeUploadExternal
+func (s *SlackWebhook) upload(c SlackChart) (bool, error) {
+	form := url.Values{}
+	form.Set("filename", filepath.Base(c.Path))
+	form.Set("length", strconv.Itoa(len(c.PNG)))
+	var ticket slackUploadTicket
+	if retry, err := s.api("files.getUploadURLExternal", "application/x-www-form-urlencoded", []byte(form.Encode()), &ticket); err != nil {
+		return retry, err
+	}
+	if _, retry, err := s.send(ticket.UploadURL, "application/octet-stream", c.PNG, ""); err != nil {
+		return retry, err
+	}
+	body, err := jsoniter.Marshal(slackCompleteUpload{
+		Files:     []slackUploadedFile{{ID: ticket.FileID, Title: c.Name}},
+		ChannelID: s.cfg.Channel,
+	})
+	if err != nil {
+		return false, err
+	}
+	return s.api("files.completeUploadExternal", "application/json; charset=utf-8", body, nil)
+}
+
+// api calls web api method with bot token, response is decoded to res if it's not nil
+func (s *SlackWebhook) api(method string, contentType string, body []byte, res interface{}) (bool, error) {
+	respBody, retry, err := s.send(strings.TrimSuffix(s.cfg.APIURL, "/")+"/"+method, contentType, body, s.cfg.BotToken)
+	if err != nil {
+		return retry, err
+	}
+	var status slackAPIResponse
+	if err := jsoniter.Unmarshal(respBody, &status); err != nil {
+		return false, fmt.Errorf("failed to decode %s response: %s", method, err)
+	}
+	if !status.OK {
+		return status.Error == "ratelimited", fmt.Errorf("slack %s failed: %s", method, status.Error)
+	}
+	if res == nil {
+		return false, nil
+	}
+	if err := jsoniter.Unmarshal(respBody, res); err != nil {
+		return false, fmt.Errorf("failed to decode %s response: %s", method, err)
+	}
+	return false, nil
+}
+
+// slackCharts renders responses chart from percs log and latency heatmap to csv dir
+func (r *Report) slackCharts() []SlackChart {
+	res := make([]SlackChart, 0)
+	responses, err := ResponsesChart(r.runName, r.percLogFilename)
+	if err != nil {
+		r.L.Error(err)
+	} else if c, err := r.renderSlackChart("responses", responses); err != nil {
+		r.L.Error(err)
+	} else {
+		res = append(res, c)
+	}
+	if r.dashboard == nil {
+		return res
+	}
+	heatmap, err := HeatmapPNGChart(r.dashboard.Heatmap, r.runName)
+	if err != nil {
+		r.L.Error(err)
+	} else if c, err := r.renderSlackChart("heatmap", heatmap); err != nil {
+		r.L.Error(err)
+	} else {
+		res = append(res, c)
+	}
+	return res
+}
+
+func (r *Report) renderSlackChart(name string, c *chart.Chart) (SlackChart, error) {
+	var b bytes.Buffer
+	if err := c.Render(chart.PNG, &b); err != nil {
+		return SlackChart{}, err
+	}
+	p := path.Join(r.reportOptions.CSVDir, fmt.Sprintf(SlackChartFile, name, r.runName))
+	if err := ioutil.WriteFile(p, b.Bytes(), 0644); err != nil {
+		return SlackChart{}, err
+	}
+	return SlackChart{Name: name, Path: p, PNG: b.Bytes()}, nil
+}
+
+// postSlack posts run to webhook unless only failures are reported and run passed,
+// charts are rendered if csv report is enabled
+func postSlack(cfg *Slack, report *Report, m *RunManifest, l *Logger) {
+	if cfg.OnlyOnFailure && m.Passed {
+		return
+	}
+	var charts []SlackChart
+	if report != nil {
+		charts = report.slackCharts()
+	}
+	if err := NewSlackWebhook(cfg).Post(NewSlackMessage(m, charts, cfg.ImagesURL), charts); err != nil {
+		l.Errorf("failed to post slack report: %s", err)
+		return
+	}
+	l.Infof("slack report posted")
+}
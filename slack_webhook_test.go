@@ -0,0 +1,225 @@
+package loaderbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+// slackStandIn records webhook posts and external file uploads, first failures webhook requests are answered with status
+type slackStandIn struct {
+	mu       *sync.Mutex
+	failures int
+	status   int
+	requests int
+	messages []SlackMessage
+	// pending uploaded files by id, shared when upload is completed
+	pending  map[string]string
+	uploaded map[string][]byte
+	files    map[string][]byte
+	titles   []string
+	channels []string
+}
+
+func newSlackStandIn(failures int, status int) *slackStandIn {
+	return &slackStandIn{
+		mu:       &sync.Mutex{},
+		failures: failures,
+		status:   status,
+		pending:  make(map[string]string),
+		uploaded: make(map[string][]byte),
+		files:    make(map[string][]byte),
+	}
+}
+
+func (s *slackStandIn) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case req.URL.Path == "/api/files.getUploadURLExternal":
+		s.uploadURL(w, req)
+		return
+	case strings.HasPrefix(req.URL.Path, "/upload/"):
+		data, _ := ioutil.ReadAll(req.Body)
+		s.uploaded[strings.TrimPrefix(req.URL.Path, "/upload/")] = data
+		_, _ = w.Write([]byte("OK"))
+		return
+	case req.URL.Path == "/api/files.completeUploadExternal":
+		s.completeUpload(w, req)
+		return
+	}
+	s.requests++
+	if s.requests <= s.failures {
+		http.Error(w, "unavailable", s.status)
+		return
+	}
+	// incoming webhooks accept json only
+	if req.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+		return
+	}
+	payload, _ := ioutil.ReadAll(req.Body)
+	var msg SlackMessage
+	if err := jsoniter.Unmarshal(payload, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.messages = append(s.messages, msg)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *slackStandIn) uploadURL(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer xoxb-token" {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"not_authed"}`))
+		return
+	}
+	if err := req.ParseForm(); err != nil || req.PostForm.Get("filename") == "" || req.PostForm.Get("length") == "" {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_arguments"}`))
+		return
+	}
+	id := fmt.Sprintf("F%d", len(s.pending)+1)
+	s.pending[id] = req.PostForm.Get("filename")
+	_, _ = fmt.Fprintf(w, `{"ok":true,"upload_url":"http://%s/upload/%s","file_id":"%s"}`, req.Host, id, id)
+}
+
+func (s *slackStandIn) completeUpload(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer xoxb-token" {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"not_authed"}`))
+		return
+	}
+	var complete slackCompleteUpload
+	if err := json.NewDecoder(req.Body).Decode(&complete); err != nil {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_json"}`))
+		return
+	}
+	for _, f := range complete.Files {
+		data, ok := s.uploaded[f.ID]
+		if !ok {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"file_not_found"}`))
+			return
+		}
+		s.files[s.pending[f.ID]] = data
+		s.titles = append(s.titles, f.Title)
+	}
+	s.channels = append(s.channels, complete.ChannelID)
+	_, _ = w.Write([]byte(`{"ok":true}`))
+}
+
+func TestCommonSlackWebhookRetries(t *testing.T) {
+	trustworthy := true
+	m := &RunManifest{
+		Name: "slack",
+		Summary: RunSummary{
+			Requests:    10,
+			Success:     0.5,
+			Errors:      map[string]int{"<timeout>": 5},
+			Trustworthy: &trustworthy,
+		},
+		Thresholds:    thresholds(1, 0.5, 10, nil),
+		FailureReason: "success ratio threshold reached",
+	}
+	m.check()
+	charts := []SlackChart{{Name: "responses", Path: "results_csv/slack_responses_slack.png", PNG: []byte("png")}}
+
+	standIn := newSlackStandIn(2, http.StatusServiceUnavailable)
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+	cfg := &Slack{WebhookURL: srv.URL, Retries: 2, RetryIntervalMs: 10, TimeoutSec: 1}
+	require.NoError(t, NewSlackWebhook(cfg).Post(NewSlackMessage(m, charts, ""), charts))
+	require.Equal(t, 3, standIn.requests)
+	// charts are not posted without images url or bot token
+	require.Empty(t, standIn.files)
+	require.Len(t, standIn.messages, 1)
+	msg := standIn.messages[0]
+	require.Equal(t, "Load test slack: FAILED", msg.Text)
+	require.Len(t, msg.Blocks, 2)
+	require.Contains(t, msg.Blocks[0].Text.Text, "Failure reason: success ratio threshold reached")
+	require.Contains(t, msg.Blocks[1].Text.Text, "FAILED success_ratio")
+	require.Contains(t, msg.Blocks[1].Text.Text, "error x5: &lt;timeout&gt;")
+
+	// charts are uploaded with bot token
+	standIn = newSlackStandIn(0, 0)
+	srvFiles := httptest.NewServer(standIn)
+	defer srvFiles.Close()
+	filesCfg := &Slack{WebhookURL: srvFiles.URL, BotToken: "xoxb-token", Channel: "C123", APIURL: srvFiles.URL + "/api", RetryIntervalMs: 10, TimeoutSec: 1}
+	require.NoError(t, NewSlackWebhook(filesCfg).Post(NewSlackMessage(m, charts, ""), charts))
+	require.Len(t, standIn.messages, 1)
+	require.Equal(t, []byte("png"), standIn.files["slack_responses_slack.png"])
+	require.Equal(t, []string{"C123"}, standIn.channels)
+	require.Equal(t, []string{"responses"}, standIn.titles)
+	filesCfg.BotToken = "invalid"
+	require.EqualError(t, NewSlackWebhook(filesCfg).Post(NewSlackMessage(m, charts, ""), charts), "slack files.getUploadURLExternal failed: not_authed")
+
+	// client errors are not retried
+	standIn = newSlackStandIn(5, http.StatusBadRequest)
+	srv400 := httptest.NewServer(standIn)
+	defer srv400.Close()
+	cfg.WebhookURL = srv400.URL
+	require.Error(t, NewSlackWebhook(cfg).Post(NewSlackMessage(m, charts, ""), charts))
+	require.Equal(t, 1, standIn.requests)
+
+	// charts are linked when images url is set
+	standIn = newSlackStandIn(0, 0)
+	srvImages := httptest.NewServer(standIn)
+	defer srvImages.Close()
+	cfg.WebhookURL = srvImages.URL
+	cfg.ImagesURL = "https://ci.example.com/artifacts/"
+	require.NoError(t, NewSlackWebhook(cfg).Post(NewSlackMessage(m, charts, cfg.ImagesURL), charts))
+	require.Empty(t, standIn.files)
+	blocks := standIn.messages[0].Blocks
+	require.Equal(t, "image", blocks[2].Type)
+	require.Equal(t, "https://ci.example.com/artifacts/slack_responses_slack.png", blocks[2].ImageURL)
+}
+
+func TestCommonSlackWebhookRunner(t *testing.T) {
+	standIn := newSlackStandIn(0, 0)
+	srv := httptest.NewServer(standIn)
+	defer srv.Close()
+	r := NewRunner(&RunnerConfig{
+		Name:            "slack_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     3,
+		ReportOptions: &ReportOptions{
+			CSVDir:  "test_csv",
+			HTMLDir: "test_html",
+			CSV:     true,
+			PNG:     true,
+		},
+		Slack: &Slack{WebhookURL: srv.URL, BotToken: "xoxb-token", Channel: "C123", APIURL: srv.URL + "/api"},
+	}, &ControlAttackerMock{}, nil)
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, standIn.messages, 1)
+	require.Equal(t, "Load test slack_runner: PASSED", standIn.messages[0].Text)
+	require.Len(t, standIn.files, 2)
+	require.Contains(t, standIn.files, "slack_responses_slack_runner.png")
+	require.Contains(t, standIn.files, "slack_heatmap_slack_runner.png")
+	require.FileExists(t, "test_csv/slack_responses_slack_runner.png")
+
+	// passed run is not posted if only failures are reported
+	r = NewRunner(&RunnerConfig{
+		Name:            "slack_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     2,
+		ReportOptions:   &ReportOptions{CSV: false},
+		Slack:           &Slack{WebhookURL: srv.URL, OnlyOnFailure: true},
+	}, &ControlAttackerMock{}, nil)
+	_, err = r.Run(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, standIn.messages, 1)
+}
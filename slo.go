@@ -0,0 +1,184 @@
+package loaderbot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ObjectiveCompliance compliance of requests with a latency objective
+type ObjectiveCompliance struct {
+	// Name objective description, e.g. "99% < 300ms"
+	Name      string        `json:"name"`
+	Target    float64       `json:"target"`
+	Threshold time.Duration `json:"threshold"`
+	// Good successful requests faster than threshold
+	Good uint64 `json:"good"`
+	// Bad failed or slow requests
+	Bad uint64 `json:"bad"`
+	// Compliance ratio of good requests
+	Compliance float64 `json:"compliance"`
+	// BudgetBurn consumed part of error budget allowed by target, budget is exhausted when > 1
+	BudgetBurn float64 `json:"budget_burn"`
+	// Met true if compliance is not less than target
+	Met bool `json:"met"`
+}
+
+func newObjectiveCompliance(o LatencyObjective) *ObjectiveCompliance {
+	return &ObjectiveCompliance{
+		Name:      fmt.Sprintf("%s%% < %dms", formatFloat(o.Target*100), o.ThresholdMs),
+		Target:    o.Target,
+		Threshold: time.Duration(o.ThresholdMs) * time.Millisecond,
+	}
+}
+
+func (o *ObjectiveCompliance) update() {
+	total := o.Good + o.Bad
+	if total == 0 {
+		o.Compliance, o.BudgetBurn, o.Met = 1, 0, true
+		return
+	}
+	o.Compliance = float64(o.Good) / float64(total)
+	o.Met = o.Compliance >= o.Target
+	budget := (1 - o.Target) * float64(total)
+	switch {
+	case budget > 0:
+		o.BudgetBurn = float64(o.Bad) / budget
+	case o.Bad > 0:
+		// zero budget is exhausted by the first bad request
+		o.BudgetBurn = float64(o.Bad)
+	default:
+		o.BudgetBurn = 0
+	}
+}
+
+// SLOMetrics Apdex score and latency objectives compliance
+type SLOMetrics struct {
+	Requests uint64 `json:"requests"`
+	// Apdex (satisfied + tolerating / 2) / requests, failed requests are frustrated, zero if Apdex T is not set
+	Apdex      float64 `json:"apdex"`
+	Satisfied  uint64  `json:"satisfied"`
+	Tolerating uint64  `json:"tolerating"`
+	// Objectives compliance, in order of SLO config
+	Objectives []*ObjectiveCompliance `json:"objectives"`
+
+	apdexT time.Duration
+}
+
+// NewSLOMetrics creates SLO metrics, nil if no SLO is set
+func NewSLOMetrics(cfg *SLO) *SLOMetrics {
+	if cfg == nil {
+		return nil
+	}
+	m := &SLOMetrics{apdexT: time.Duration(cfg.ApdexTMs) * time.Millisecond}
+	for _, o := range cfg.Objectives {
+		m.Objectives = append(m.Objectives, newObjectiveCompliance(o))
+	}
+	return m
+}
+
+func (m *SLOMetrics) add(r AttackResult) {
+	m.Requests++
+	code := r.DoResult.StatusCode
+	failed := r.DoResult.Error != "" || (code != 0 && (code < 200 || code >= 400))
+	if !failed && m.apdexT > 0 {
+		switch {
+		case r.Elapsed <= m.apdexT:
+			m.Satisfied++
+		case r.Elapsed <= 4*m.apdexT:
+			m.Tolerating++
+		}
+	}
+	for _, o := range m.Objectives {
+		if !failed && r.Elapsed <= o.Threshold {
+			o.Good++
+		} else {
+			o.Bad++
+		}
+	}
+}
+
+// merge adds counts of other metrics with the same config
+func (m *SLOMetrics) merge(other *SLOMetrics) {
+	m.Requests += other.Requests
+	m.Satisfied += other.Satisfied
+	m.Tolerating += other.Tolerating
+	for i, o := range other.Objectives {
+		m.Objectives[i].Good += o.Good
+		m.Objectives[i].Bad += o.Bad
+	}
+}
+
+func (m *SLOMetrics) update() {
+	if m.apdexT > 0 && m.Requests > 0 {
+		m.Apdex = (float64(m.Satisfied) + float64(m.Tolerating)/2) / float64(m.Requests)
+	}
+	for _, o := range m.Objectives {
+		o.update()
+	}
+}
+
+// Met true if all objectives are met
+func (m *SLOMetrics) Met() bool {
+	for _, o := range m.Objectives {
+		if !o.Met {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *SLOMetrics) String() string {
+	res := make([]string, 0)
+	if m.apdexT > 0 {
+		res = append(res, fmt.Sprintf("apdex(T=%v) [%.4f]", m.apdexT, m.Apdex))
+	}
+	for _, o := range m.Objectives {
+		res = append(res, fmt.Sprintf("%s: compliance [%.4f] budget burn [%.4f] met [%t]", o.Name, o.Compliance, o.BudgetBurn, o.Met))
+	}
+	return strings.Join(res, ", ")
+}
+
+// SLOReport SLO compliance of a run and its steps
+type SLOReport struct {
+	Run   *SLOMetrics         `json:"run"`
+	Steps map[int]*SLOMetrics `json:"steps"`
+
+	cfg *SLO
+}
+
+// NewSLOReport creates SLO report, nil if no SLO is set
+func NewSLOReport(cfg *SLO) *SLOReport {
+	if cfg == nil {
+		return nil
+	}
+	return &SLOReport{
+		Run:   NewSLOMetrics(cfg),
+		Steps: make(map[int]*SLOMetrics),
+		cfg:   cfg,
+	}
+}
+
+// addTick aggregates tick SLO metrics into step and run
+func (s *SLOReport) addTick(step int, tick *SLOMetrics) {
+	if tick == nil {
+		return
+	}
+	if _, ok := s.Steps[step]; !ok {
+		s.Steps[step] = NewSLOMetrics(s.cfg)
+	}
+	s.Steps[step].merge(tick)
+	s.Steps[step].update()
+	s.Run.merge(tick)
+	s.Run.update()
+}
+
+func (s *SLOReport) sortedSteps() []int {
+	steps := make([]int, 0, len(s.Steps))
+	for step := range s.Steps {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	return steps
+}
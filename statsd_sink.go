@@ -0,0 +1,106 @@
+package loaderbot
+
+import (
+	"errors"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statsdMaxPacket keeps datagrams under common network mtu
+const statsdMaxPacket = 1432
+
+var invalidStatsDChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+// StatsDSink sends tick metrics as gauges over udp,
+// plain statsd has runner and node in metric names, DogStatsD has them as tags
+type StatsDSink struct {
+	cfg  *StatsD
+	tags []string
+	conn net.Conn
+}
+
+// NewStatsDSink creates sink sending to cfg.Addr
+func NewStatsDSink(cfg *StatsD) (*StatsDSink, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("no statsd address")
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(cfg.Tags))
+	for k, v := range cfg.Tags {
+		tags = append(tags, statsdTag(k, v))
+	}
+	sort.Strings(tags)
+	return &StatsDSink{cfg: cfg, tags: tags, conn: conn}, nil
+}
+
+// ReportTick sends tick gauges, lines are batched into datagrams, send errors are ignored as with any udp metrics
+func (s *StatsDSink) ReportTick(t SinkTick) {
+	for _, packet := range statsdPackets(s.statsdLines(t)) {
+		_, _ = s.conn.Write(packet)
+	}
+}
+
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsDSink) statsdLines(t SinkTick) []string {
+	prefix := s.cfg.Prefix
+	var suffix string
+	if s.cfg.DogStatsD {
+		tags := []string{statsdTag("runner", t.Runner), "step:" + strconv.Itoa(t.Step)}
+		if t.Node != AggregatedNode {
+			tags = append(tags, statsdTag("node", t.Node))
+		}
+		suffix = "|#" + strings.Join(append(tags, s.tags...), ",")
+	} else {
+		prefix += statsdName(t.Runner) + "."
+		if t.Node != AggregatedNode {
+			prefix += statsdName(t.Node) + "."
+		}
+	}
+	lines := make([]string, 0)
+	for _, f := range sinkFields(t) {
+		if math.IsNaN(f.value) || math.IsInf(f.value, 0) {
+			continue
+		}
+		lines = append(lines, prefix+statsdName(f.name)+":"+strconv.FormatFloat(f.value, 'f', -1, 64)+"|g"+suffix)
+	}
+	return lines
+}
+
+// statsdPackets joins lines with newlines into datagrams not larger than statsdMaxPacket
+func statsdPackets(lines []string) [][]byte {
+	packets := make([][]byte, 0)
+	var cur []byte
+	for _, l := range lines {
+		if len(cur) > 0 && len(cur)+1+len(l) > statsdMaxPacket {
+			packets = append(packets, cur)
+			cur = nil
+		}
+		if len(cur) > 0 {
+			cur = append(cur, '\n')
+		}
+		cur = append(cur, l...)
+	}
+	if len(cur) > 0 {
+		packets = append(packets, cur)
+	}
+	return packets
+}
+
+func statsdName(s string) string {
+	return invalidStatsDChars.ReplaceAllString(s, "_")
+}
+
+// statsdTag dogstatsd tag, separators are not allowed in tag values
+func statsdTag(k, v string) string {
+	return statsdName(k) + ":" + strings.NewReplacer(",", "_", "|", "_", "#", "_").Replace(v)
+}
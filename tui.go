@@ -0,0 +1,336 @@
+package loaderbot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// tuiRefreshInterval how often terminal ui is redrawn
+	tuiRefreshInterval = 500 * time.Millisecond
+	// tuiSparklineWidth ticks shown in percentile sparklines
+	tuiSparklineWidth = 60
+	// tuiTopErrors amount of most frequent errors shown
+	tuiTopErrors = 5
+	// pauseCheckInterval how often paused scheduler checks if it's resumed
+	pauseCheckInterval = 100 * time.Millisecond
+	tuiClearScreen     = "\033[H\033[2J"
+)
+
+var sparklineBars = []rune("▁▂▃▄▅▆▇█")
+
+// isTerminal true if file is a character device, e.g. stdout is not redirected to file or pipe
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalUI shows run state redrawn in place instead of tick log lines
+type terminalUI struct {
+	mu        *sync.Mutex
+	out       io.Writer
+	name      string
+	mode      SystemMode
+	start     time.Time
+	duration  time.Duration
+	ticks     int
+	last      DashboardTick
+	p50       []float64
+	p95       []float64
+	p99       []float64
+	errors    map[string]int
+	paused    bool
+	stopped   bool
+	saturated bool
+	keys      string
+}
+
+func newTerminalUI(out io.Writer, cfg *RunnerConfig, start time.Time) *terminalUI {
+	return &terminalUI{
+		mu:       &sync.Mutex{},
+		out:      out,
+		name:     cfg.Name,
+		mode:     cfg.SystemMode,
+		start:    start,
+		duration: time.Duration(cfg.TestTimeSec) * time.Second,
+		errors:   make(map[string]int),
+		keys:     "q: stop, p: pause/resume",
+	}
+}
+
+// addTick updates state with reported tick and its results
+func (t *terminalUI) addTick(token attackToken, attackers int, m *Metrics, samples []AttackResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ticks++
+	t.last = DashboardTick{
+		Tick:      token.Tick,
+		Step:      token.Step,
+		TargetRPS: int(m.TargetRate),
+		Attackers: attackers,
+		Rate:      m.Rate,
+		Requests:  m.Requests,
+		Success:   m.Success,
+		P50:       durationMs(m.Latencies.P50),
+		P95:       durationMs(m.Latencies.P95),
+		P99:       durationMs(m.Latencies.P99),
+	}
+	t.p50 = appendWindow(t.p50, t.last.P50)
+	t.p95 = appendWindow(t.p95, t.last.P95)
+	t.p99 = appendWindow(t.p99, t.last.P99)
+	for _, s := range samples {
+		if s.DoResult.Error != "" {
+			t.errors[s.DoResult.Error]++
+		}
+	}
+}
+
+func (t *terminalUI) setPaused(paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = paused
+}
+
+func (t *terminalUI) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
+
+func (t *terminalUI) setSaturated(saturated bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.saturated = saturated
+}
+
+func appendWindow(values []float64, v float64) []float64 {
+	values = append(values, v)
+	if len(values) > tuiSparklineWidth {
+		values = values[len(values)-tuiSparklineWidth:]
+	}
+	return values
+}
+
+// sparkline draws values as bars scaled from zero to max
+func sparkline(values []float64, max float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if max > 0 {
+			idx = int(math.Round(v / max * float64(len(sparklineBars)-1)))
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(sparklineBars) {
+			idx = len(sparklineBars) - 1
+		}
+		b.WriteRune(sparklineBars[idx])
+	}
+	return b.String()
+}
+
+// render current state as text
+func (t *terminalUI) render(now time.Time) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	state := "running"
+	switch {
+	case t.stopped:
+		state = "stopping"
+	case t.paused:
+		state = "paused"
+	}
+	elapsed := now.Sub(t.start).Truncate(time.Second)
+	remaining := (t.duration - elapsed).Truncate(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	fmt.Fprintf(&b, "%s [%s] mode: %s\n", t.name, state, t.mode)
+	fmt.Fprintf(&b, "elapsed: %v, remaining: %v\n\n", elapsed, remaining)
+	if t.ticks == 0 {
+		b.WriteString("waiting for the first tick\n")
+	} else {
+		l := t.last
+		fmt.Fprintf(&b, "step: %d, tick: %d, attackers: %d\n", l.Step, l.Tick, l.Attackers)
+		fmt.Fprintf(&b, "rate: %.2f -> %d rps, requests: %d, success: %.4f\n\n", l.Rate, l.TargetRPS, l.Requests, l.Success)
+		var max float64
+		for _, v := range t.p99 {
+			max = math.Max(max, v)
+		}
+		// all percentiles share scale to be comparable
+		fmt.Fprintf(&b, "p50 %8.2fms %s\n", l.P50, sparkline(t.p50, max))
+		fmt.Fprintf(&b, "p95 %8.2fms %s\n", l.P95, sparkline(t.p95, max))
+		fmt.Fprintf(&b, "p99 %8.2fms %s\n", l.P99, sparkline(t.p99, max))
+	}
+	if t.saturated {
+		b.WriteString("\nload generator is saturated, results may be wrong\n")
+	}
+	b.WriteString("\ntop errors:\n")
+	errs := topErrors(t.errors, tuiTopErrors)
+	if len(errs) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, e := range errs {
+		fmt.Fprintf(&b, "  %6d %s\n", t.errors[e], e)
+	}
+	fmt.Fprintf(&b, "\n%s\n", t.keys)
+	return b.String()
+}
+
+func (t *terminalUI) draw(now time.Time) {
+	_, _ = io.WriteString(t.out, tuiClearScreen+t.render(now))
+}
+
+// startTUI shows terminal ui if it's enabled and stdout is a terminal, otherwise ticks are logged as usual,
+// tick log lines are hidden while ui is shown, returns func to call after the run
+func (r *Runner) startTUI() func() {
+	if !r.Cfg.TUI {
+		return func() {}
+	}
+	if !isTerminal(os.Stdout) {
+		r.L.Infof("stdout is not a terminal, terminal ui is disabled")
+		return func() {}
+	}
+	r.tui = newTerminalUI(os.Stdout, r.Cfg, time.Now())
+	level := r.L.Level()
+	r.L.SetLevel(zapcore.ErrorLevel)
+	restoreTerminal := func() {}
+	done := make(chan struct{})
+	keysExited := make(chan struct{})
+	if isTerminal(os.Stdin) {
+		if restore, err := rawTerminal(os.Stdin); err == nil {
+			restoreTerminal = restore
+		} else {
+			r.tui.keys = "q + enter: stop, p + enter: pause/resume"
+		}
+		go func() {
+			defer close(keysExited)
+			r.handleKeys(stdinKeys(), done)
+		}()
+	} else {
+		close(keysExited)
+	}
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(tuiRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				r.tui.setSaturated(!r.Trustworthy())
+				r.tui.draw(now)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+		<-keysExited
+		r.tui.draw(time.Now())
+		restoreTerminal()
+		r.L.SetLevel(level)
+		r.tui.mu.Lock()
+		defer r.tui.mu.Unlock()
+		if r.tui.stopped {
+			r.L.Infof("stopped by user")
+		}
+	}
+}
+
+var (
+	stdinKeysOnce = &sync.Once{}
+	stdinKeysCh   <-chan rune
+)
+
+// stdinKeys keys of one stdin reader shared by all runs, blocked stdin read can't be interrupted,
+// so reader is started once instead of per run
+func stdinKeys() <-chan rune {
+	stdinKeysOnce.Do(func() {
+		stdinKeysCh = keyReader(os.Stdin)
+	})
+	return stdinKeysCh
+}
+
+// keyReader sends runes read from in, channel is closed on read error
+func keyReader(in io.Reader) <-chan rune {
+	keys := make(chan rune)
+	go func() {
+		defer close(keys)
+		reader := bufio.NewReader(in)
+		for {
+			key, _, err := reader.ReadRune()
+			if err != nil {
+				return
+			}
+			keys <- key
+		}
+	}()
+	return keys
+}
+
+// handleKeys stops or pauses the run by key pressed until run ends or done is closed
+func (r *Runner) handleKeys(keys <-chan rune, done <-chan struct{}) {
+	for {
+		var key rune
+		select {
+		case <-r.TimeoutCtx.Done():
+			return
+		case <-done:
+			return
+		case k, ok := <-keys:
+			if !ok {
+				return
+			}
+			key = k
+		}
+		switch key {
+		case 'q':
+			if r.tui != nil {
+				r.tui.stop()
+			}
+			r.CancelFunc()
+			return
+		case 'p':
+			if r.Paused() {
+				r.Resume()
+			} else {
+				r.Pause()
+			}
+			if r.tui != nil {
+				r.tui.setPaused(r.Paused())
+			}
+		}
+	}
+}
+
+// Pause stops scheduling new attacks until Resume is called, test timeout is not extended
+func (r *Runner) Pause() {
+	atomic.StoreInt32(&r.paused, 1)
+}
+
+// Resume continues scheduling attacks after Pause
+func (r *Runner) Resume() {
+	atomic.StoreInt32(&r.paused, 0)
+}
+
+// Paused true if attacks are not scheduled
+func (r *Runner) Paused() bool {
+	return atomic.LoadInt32(&r.paused) == 1
+}
@@ -0,0 +1,130 @@
+package loaderbot
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonTerminalUIRender(t *testing.T) {
+	start := time.Now()
+	ui := newTerminalUI(&bytes.Buffer{}, &RunnerConfig{Name: "tui", SystemMode: BoundRPS, TestTimeSec: 60}, start)
+	require.Contains(t, ui.render(start), "waiting for the first tick")
+	for tick := 1; tick <= 3; tick++ {
+		token := attackToken{TargetRPS: 10, Step: 1, Tick: tick}
+		m := NewMetrics()
+		samples := make([]AttackResult, 0)
+		for i := 0; i < 10; i++ {
+			res := AttackResult{
+				AttackToken: token,
+				Begin:       start,
+				End:         start,
+				Elapsed:     time.Duration(tick*10) * time.Millisecond,
+			}
+			if i < tick {
+				res.DoResult.Error = "timeout"
+			}
+			if i == 0 && tick == 3 {
+				res.DoResult.Error = "connection refused"
+			}
+			m.add(res)
+			samples = append(samples, res)
+		}
+		m.TargetRate = float64(token.TargetRPS)
+		m.update()
+		ui.addTick(token, 5, m, samples)
+	}
+	ui.setPaused(true)
+	out := ui.render(start.Add(10 * time.Second))
+	require.Contains(t, out, "tui [paused] mode: BoundRPS")
+	require.Contains(t, out, "elapsed: 10s, remaining: 50s")
+	require.Contains(t, out, "step: 1, tick: 3, attackers: 5")
+	require.Contains(t, out, "-> 10 rps, requests: 10, success: 0.7000")
+	require.Contains(t, out, "p99    30.00ms ▃▆█")
+	lines := strings.Split(out, "\n")
+	for i, l := range lines {
+		if l == "top errors:" {
+			require.Equal(t, "       5 timeout", lines[i+1])
+			require.Equal(t, "       1 connection refused", lines[i+2])
+		}
+	}
+	require.Equal(t, "▁▁█", sparkline([]float64{0, -1, 10}, 10))
+}
+
+func TestCommonTerminalUIKeys(t *testing.T) {
+	r := &Runner{}
+	r.TimeoutCtx, r.CancelFunc = context.WithCancel(context.Background())
+	r.handleKeys(keyReader(strings.NewReader("p")), nil)
+	require.True(t, r.Paused())
+	r.handleKeys(keyReader(strings.NewReader("pxp")), nil)
+	require.True(t, r.Paused())
+	r.handleKeys(keyReader(strings.NewReader("pq")), nil)
+	require.False(t, r.Paused())
+	require.Error(t, r.TimeoutCtx.Err())
+}
+
+func TestCommonTerminalUIKeysStop(t *testing.T) {
+	r := &Runner{}
+	r.TimeoutCtx, r.CancelFunc = context.WithCancel(context.Background())
+	keys := make(chan rune)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		r.handleKeys(keys, done)
+	}()
+	keys <- 'p'
+	// handler is stopped with ui while no keys are pressed
+	close(done)
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("keys handler is not stopped")
+	}
+	require.True(t, r.Paused())
+	require.NoError(t, r.TimeoutCtx.Err())
+
+	// and when run ends
+	done = make(chan struct{})
+	exited = make(chan struct{})
+	go func() {
+		defer close(exited)
+		r.handleKeys(keys, done)
+	}()
+	r.CancelFunc()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("keys handler is not stopped")
+	}
+}
+
+func TestCommonRunnerPause(t *testing.T) {
+	r := NewRunner(&RunnerConfig{
+		Name:            "pause_runner",
+		SystemMode:      BoundRPS,
+		Attackers:       10,
+		AttackerTimeout: 1,
+		StartRPS:        10,
+		TestTimeSec:     5,
+		ReportOptions: &ReportOptions{
+			CSV: false,
+		},
+		// stdout is not a terminal in tests, ticks are logged
+		TUI: true,
+	}, &ControlAttackerMock{}, nil)
+	r.Pause()
+	go func() {
+		time.Sleep(3 * time.Second)
+		r.Resume()
+	}()
+	_, err := r.Run(context.TODO())
+	require.NoError(t, err)
+	require.Nil(t, r.tui)
+	require.LessOrEqual(t, r.RunMetrics.Requests, uint64(30))
+	require.NotZero(t, r.RunMetrics.Requests)
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package loaderbot
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// rawTerminal makes keys available without enter and disables echo, returns func restoring terminal state
+func rawTerminal(f *os.File) (func(), error) {
+	state, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(f, "-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = stty(f, strings.TrimSpace(state))
+	}, nil
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return string(out), err
+}
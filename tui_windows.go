@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package loaderbot
+
+import (
+	"errors"
+	"os"
+)
+
+// rawTerminal is not supported on windows, keys are read after enter
+func rawTerminal(_ *os.File) (func(), error) {
+	return nil, errors.New("raw terminal is not supported")
+}
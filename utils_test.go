@@ -24,6 +24,7 @@ func withControllableAttackers(cfg ControllableConfig) {
 		attackers = append(attackers, NewControlMockAttacker(i, cfg.ControlChan, cfg.R))
 	}
 	cfg.R.attackers = attackers
+	cfg.R.attackersCount = int64(len(attackers))
 }
 
 // nolint